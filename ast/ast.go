@@ -24,6 +24,7 @@ type Expression interface {
 
 type Program struct {
 	Statements []Statement
+	Comments   []token.Comment // all comments of the source, in order
 }
 
 func (p *Program) TokenLiteral() string {
@@ -43,8 +44,9 @@ func (p *Program) String() string {
 }
 
 type LetStatement struct {
-	Token token.Token // the token.LET token
+	Token token.Token // the token.LET or token.CONST token
 	Name  *Identifier
+	Type  TypeExpression // nil without annotation
 	Value Expression
 }
 
@@ -56,11 +58,19 @@ func (ls *LetStatement) TokenLiteral() string {
 	return ls.Token.Literal
 }
 
+// IsConst reports whether the binding was declared with `const`
+func (ls *LetStatement) IsConst() bool {
+	return ls.Token.Type == token.CONST
+}
+
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(ls.TokenLiteral() + " ")
 	out.WriteString(ls.Name.String())
+	if ls.Type != nil {
+		out.WriteString(": " + ls.Type.String())
+	}
 	out.WriteString(" = ")
 
 	if ls.Value != nil {
@@ -146,6 +156,15 @@ func (il *IntegerLiteral) String() string {
 	return il.Token.Literal
 }
 
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
+
 type StringLiteral struct {
 	Token token.Token // the ':'
 	Value string
@@ -218,6 +237,7 @@ func (b *Boolean) String() string {
 type BlockStatement struct {
 	Token      token.Token // the { token
 	Statements []Statement
+	Rbrace     token.Position // where the closing } is
 }
 
 func (bc *BlockStatement) statementNode() {}
@@ -268,6 +288,11 @@ type FunctionLiteral struct {
 	Parameters []*Identifier
 	Body       *BlockStatement
 	Name       string
+
+	// the optional annotations, ParameterTypes has an entry, maybe nil, for
+	// each parameter if any of them is annotated
+	ParameterTypes []TypeExpression
+	ReturnType     TypeExpression
 }
 
 func (fl *FunctionLiteral) expressionNode() {}
@@ -278,8 +303,12 @@ func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
 	params := []string{}
-	for _, param := range fl.Parameters {
-		params = append(params, param.String())
+	for i, param := range fl.Parameters {
+		if i < len(fl.ParameterTypes) && fl.ParameterTypes[i] != nil {
+			params = append(params, param.String()+": "+fl.ParameterTypes[i].String())
+		} else {
+			params = append(params, param.String())
+		}
 	}
 
 	out.WriteString("fn ")
@@ -287,10 +316,37 @@ func (fl *FunctionLiteral) String() string {
 		out.WriteString(fmt.Sprintf("<%s>", fl.Name))
 	}
 	out.WriteString("(" + strings.Join(params, ",") + ")")
+	if fl.ReturnType != nil {
+		out.WriteString(" -> " + fl.ReturnType.String())
+	}
 	out.WriteString(fl.Body.String())
 	return out.String()
 }
 
+// MacroLiteral is macro(params) { body }, the body builds the expansion of
+// a call from the quoted arguments
+type MacroLiteral struct {
+	Token      token.Token // the 'macro' token
+	Parameters []*Identifier
+	Body       *BlockStatement
+}
+
+func (ml *MacroLiteral) expressionNode()      {}
+func (ml *MacroLiteral) TokenLiteral() string { return ml.Token.Literal }
+func (ml *MacroLiteral) String() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, param := range ml.Parameters {
+		params = append(params, param.String())
+	}
+
+	out.WriteString("macro")
+	out.WriteString("(" + strings.Join(params, ", ") + ")")
+	out.WriteString(ml.Body.String())
+	return out.String()
+}
+
 type CallExpression struct {
 	Token     token.Token // the '(' token
 	Function  Expression  // the identifier or functionLiteral
@@ -361,9 +417,42 @@ func (ie *IndexExpression) String() string {
 	return out.String()
 }
 
+// SliceExpression is left[start:end] or left[start:end:step], any of the
+// bounds may be missing and then is nil
+type SliceExpression struct {
+	Token token.Token // the [ token
+	Left  Expression
+	Start Expression
+	End   Expression
+	Step  Expression
+}
+
+func (se *SliceExpression) expressionNode()      {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(se.Left.String())
+	out.WriteString("[")
+	if se.Start != nil {
+		out.WriteString(se.Start.String())
+	}
+	out.WriteString(":")
+	if se.End != nil {
+		out.WriteString(se.End.String())
+	}
+	if se.Step != nil {
+		out.WriteString(":")
+		out.WriteString(se.Step.String())
+	}
+	out.WriteString("]")
+	return out.String()
+}
+
 type HashLiteral struct {
 	Token token.Token // the '{' token
 	Pairs map[Expression]Expression
+	Keys  []Expression // the keys of Pairs in source order
 }
 
 func (hl *HashLiteral) expressionNode()      {}
@@ -371,11 +460,68 @@ func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
 func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 	pairs := []string{}
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	for _, key := range hl.Keys {
+		pairs = append(pairs, key.String()+":"+hl.Pairs[key].String())
 	}
 	out.WriteString("{")
 	out.WriteString(strings.Join(pairs, ", "))
 	out.WriteString("}")
 	return out.String()
 }
+
+// InterpolatedString is "s0${e0}s1${e1}s2", there is one string more than
+// expressions
+type InterpolatedString struct {
+	Token       token.Token // the TEMPLATE_START token
+	Strings     []string
+	Expressions []Expression
+}
+
+func (is *InterpolatedString) expressionNode()      {}
+func (is *InterpolatedString) TokenLiteral() string { return is.Token.Literal }
+func (is *InterpolatedString) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("\"")
+	for i, e := range is.Expressions {
+		out.WriteString(is.Strings[i])
+		out.WriteString("${")
+		out.WriteString(e.String())
+		out.WriteString("}")
+	}
+	out.WriteString(is.Strings[len(is.Strings)-1])
+	out.WriteString("\"")
+	return out.String()
+}
+
+type SetLiteral struct {
+	Token    token.Token // the '#{' token
+	Elements []Expression
+}
+
+func (sl *SetLiteral) expressionNode()      {}
+func (sl *SetLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *SetLiteral) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range sl.Elements {
+		elements = append(elements, el.String())
+	}
+
+	out.WriteString("#{")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("}")
+	return out.String()
+}
+
+type ImportExpression struct {
+	Token token.Token // the 'import' token
+	Path  Expression  // the path of module source file
+}
+
+func (ie *ImportExpression) expressionNode()      {}
+func (ie *ImportExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *ImportExpression) String() string {
+	return fmt.Sprintf("import %q", ie.Path.String())
+}
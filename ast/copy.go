@@ -0,0 +1,195 @@
+package ast
+
+import "fmt"
+
+// Copy returns a deep copy of the tree of node, so that the copy can be
+// rewritten without changing the original. Tokens and type annotations,
+// which Rewrite leaves alone, are shared.
+func Copy(node Node) Node {
+	switch n := node.(type) {
+	case *Program:
+		c := *n
+		c.Statements = copyStatements(n.Statements)
+		return &c
+
+	case *LetStatement:
+		c := *n
+		c.Name = copyIdentifier(n.Name)
+		c.Value = copyExpression(n.Value)
+		return &c
+
+	case *ReturnStatement:
+		c := *n
+		c.ReturnValue = copyExpression(n.ReturnValue)
+		return &c
+
+	case *ExpressionStatement:
+		c := *n
+		c.Expression = copyExpression(n.Expression)
+		return &c
+
+	case *BlockStatement:
+		c := *n
+		c.Statements = copyStatements(n.Statements)
+		return &c
+
+	case *Identifier:
+		c := *n
+		return &c
+
+	case *IntegerLiteral:
+		c := *n
+		return &c
+
+	case *FloatLiteral:
+		c := *n
+		return &c
+
+	case *StringLiteral:
+		c := *n
+		return &c
+
+	case *Boolean:
+		c := *n
+		return &c
+
+	case *PrefixExpression:
+		c := *n
+		c.Right = copyExpression(n.Right)
+		return &c
+
+	case *InfixExpression:
+		c := *n
+		c.Left = copyExpression(n.Left)
+		c.Right = copyExpression(n.Right)
+		return &c
+
+	case *IfExpression:
+		c := *n
+		c.Condition = copyExpression(n.Condition)
+		c.Consequence = copyBlock(n.Consequence)
+		c.Alternative = copyBlock(n.Alternative)
+		return &c
+
+	case *FunctionLiteral:
+		c := *n
+		c.Parameters = copyIdentifiers(n.Parameters)
+		c.ParameterTypes = append([]TypeExpression(nil), n.ParameterTypes...)
+		c.Body = copyBlock(n.Body)
+		return &c
+
+	case *MacroLiteral:
+		c := *n
+		c.Parameters = copyIdentifiers(n.Parameters)
+		c.Body = copyBlock(n.Body)
+		return &c
+
+	case *CallExpression:
+		c := *n
+		c.Function = copyExpression(n.Function)
+		c.Arguments = copyExpressions(n.Arguments)
+		return &c
+
+	case *ArrayLiteral:
+		c := *n
+		c.Elements = copyExpressions(n.Elements)
+		return &c
+
+	case *SetLiteral:
+		c := *n
+		c.Elements = copyExpressions(n.Elements)
+		return &c
+
+	case *IndexExpression:
+		c := *n
+		c.Left = copyExpression(n.Left)
+		c.Index = copyExpression(n.Index)
+		return &c
+
+	case *SliceExpression:
+		c := *n
+		c.Left = copyExpression(n.Left)
+		c.Start = copyExpression(n.Start)
+		c.End = copyExpression(n.End)
+		c.Step = copyExpression(n.Step)
+		return &c
+
+	case *HashLiteral:
+		c := *n
+		c.Keys = make([]Expression, len(n.Keys))
+		c.Pairs = make(map[Expression]Expression, len(n.Keys))
+		for i, key := range n.Keys {
+			c.Keys[i] = copyExpression(key)
+			c.Pairs[c.Keys[i]] = copyExpression(n.Pairs[key])
+		}
+		return &c
+
+	case *InterpolatedString:
+		c := *n
+		c.Strings = append([]string{}, n.Strings...)
+		c.Expressions = copyExpressions(n.Expressions)
+		return &c
+
+	case *ImportExpression:
+		c := *n
+		c.Path = copyExpression(n.Path)
+		return &c
+	}
+	panic(fmt.Sprintf("ast.Copy: unexpected node type %T", node))
+}
+
+func copyStatements(stmts []Statement) []Statement {
+	if stmts == nil {
+		return nil
+	}
+	c := make([]Statement, len(stmts))
+	for i, s := range stmts {
+		if s != nil {
+			c[i] = Copy(s).(Statement)
+		}
+	}
+	return c
+}
+
+func copyExpressions(exps []Expression) []Expression {
+	if exps == nil {
+		return nil
+	}
+	c := make([]Expression, len(exps))
+	for i, e := range exps {
+		c[i] = copyExpression(e)
+	}
+	return c
+}
+
+func copyExpression(e Expression) Expression {
+	if e == nil {
+		return nil
+	}
+	return Copy(e).(Expression)
+}
+
+func copyIdentifiers(idents []*Identifier) []*Identifier {
+	if idents == nil {
+		return nil
+	}
+	c := make([]*Identifier, len(idents))
+	for i, ident := range idents {
+		c[i] = copyIdentifier(ident)
+	}
+	return c
+}
+
+func copyIdentifier(ident *Identifier) *Identifier {
+	if ident == nil {
+		return nil
+	}
+	return Copy(ident).(*Identifier)
+}
+
+func copyBlock(block *BlockStatement) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	return Copy(block).(*BlockStatement)
+}
@@ -0,0 +1,144 @@
+package ast
+
+import "fmt"
+
+// Rewrite transforms the tree of node bottom-up: the children of a node are
+// rewritten first, then the node is replaced by what f returns for it, f
+// returns its argument to keep a node. The tree is changed in place and the
+// new root is returned. A replacement that doesn't fit the place of the node,
+// e.g. an integer for a parameter, is dropped and the node kept. Type
+// annotations are left alone.
+func Rewrite(node Node, f func(Node) Node) Node {
+	switch n := node.(type) {
+	case *Program:
+		rewriteStatements(n.Statements, f)
+
+	case *LetStatement:
+		n.Name = rewriteIdentifier(n.Name, f)
+		n.Value = rewriteExpression(n.Value, f)
+
+	case *ReturnStatement:
+		n.ReturnValue = rewriteExpression(n.ReturnValue, f)
+
+	case *ExpressionStatement:
+		n.Expression = rewriteExpression(n.Expression, f)
+
+	case *BlockStatement:
+		rewriteStatements(n.Statements, f)
+
+	case *Identifier, *IntegerLiteral, *FloatLiteral, *StringLiteral, *Boolean:
+		// no children
+
+	case *PrefixExpression:
+		n.Right = rewriteExpression(n.Right, f)
+
+	case *InfixExpression:
+		n.Left = rewriteExpression(n.Left, f)
+		n.Right = rewriteExpression(n.Right, f)
+
+	case *IfExpression:
+		n.Condition = rewriteExpression(n.Condition, f)
+		n.Consequence = rewriteBlock(n.Consequence, f)
+		n.Alternative = rewriteBlock(n.Alternative, f)
+
+	case *FunctionLiteral:
+		for i, param := range n.Parameters {
+			n.Parameters[i] = rewriteIdentifier(param, f)
+		}
+		n.Body = rewriteBlock(n.Body, f)
+
+	case *MacroLiteral:
+		for i, param := range n.Parameters {
+			n.Parameters[i] = rewriteIdentifier(param, f)
+		}
+		n.Body = rewriteBlock(n.Body, f)
+
+	case *CallExpression:
+		n.Function = rewriteExpression(n.Function, f)
+		rewriteExpressions(n.Arguments, f)
+
+	case *ArrayLiteral:
+		rewriteExpressions(n.Elements, f)
+
+	case *SetLiteral:
+		rewriteExpressions(n.Elements, f)
+
+	case *IndexExpression:
+		n.Left = rewriteExpression(n.Left, f)
+		n.Index = rewriteExpression(n.Index, f)
+
+	case *SliceExpression:
+		n.Left = rewriteExpression(n.Left, f)
+		n.Start = rewriteExpression(n.Start, f)
+		n.End = rewriteExpression(n.End, f)
+		n.Step = rewriteExpression(n.Step, f)
+
+	case *HashLiteral:
+		// the keys are the identities of the pairs, so both get rebuilt
+		pairs := make(map[Expression]Expression, len(n.Keys))
+		for i, key := range n.Keys {
+			value := n.Pairs[key]
+			n.Keys[i] = rewriteExpression(key, f)
+			pairs[n.Keys[i]] = rewriteExpression(value, f)
+		}
+		n.Pairs = pairs
+
+	case *InterpolatedString:
+		rewriteExpressions(n.Expressions, f)
+
+	case *ImportExpression:
+		n.Path = rewriteExpression(n.Path, f)
+
+	default:
+		panic(fmt.Sprintf("ast.Rewrite: unexpected node type %T", n))
+	}
+
+	return f(node)
+}
+
+func rewriteStatements(stmts []Statement, f func(Node) Node) {
+	for i, s := range stmts {
+		if s == nil {
+			continue
+		}
+		if stmt, ok := Rewrite(s, f).(Statement); ok {
+			stmts[i] = stmt
+		}
+	}
+}
+
+func rewriteExpressions(exps []Expression, f func(Node) Node) {
+	for i, e := range exps {
+		exps[i] = rewriteExpression(e, f)
+	}
+}
+
+func rewriteExpression(e Expression, f func(Node) Node) Expression {
+	if e == nil {
+		return nil
+	}
+	if exp, ok := Rewrite(e, f).(Expression); ok {
+		return exp
+	}
+	return e
+}
+
+func rewriteIdentifier(ident *Identifier, f func(Node) Node) *Identifier {
+	if ident == nil {
+		return nil
+	}
+	if r, ok := Rewrite(ident, f).(*Identifier); ok {
+		return r
+	}
+	return ident
+}
+
+func rewriteBlock(block *BlockStatement, f func(Node) Node) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	if r, ok := Rewrite(block, f).(*BlockStatement); ok {
+		return r
+	}
+	return block
+}
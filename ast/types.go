@@ -0,0 +1,80 @@
+package ast
+
+import (
+	"bytes"
+	"monkey/token"
+	"strings"
+)
+
+// TypeExpression is an optional type annotation, e.g. the int of
+// `let x: int = 1`
+type TypeExpression interface {
+	Node
+	typeNode()
+}
+
+// NamedType is a basic type, e.g. int, string or any
+type NamedType struct {
+	Token token.Token // the token.IDENT token
+	Name  string
+}
+
+func (nt *NamedType) typeNode()            {}
+func (nt *NamedType) TokenLiteral() string { return nt.Token.Literal }
+func (nt *NamedType) String() string       { return nt.Name }
+
+// ArrayType is [T], an array of T
+type ArrayType struct {
+	Token   token.Token // the '[' token
+	Element TypeExpression
+}
+
+func (at *ArrayType) typeNode()            {}
+func (at *ArrayType) TokenLiteral() string { return at.Token.Literal }
+func (at *ArrayType) String() string       { return "[" + at.Element.String() + "]" }
+
+// HashType is {K: V}, a hash from K to V
+type HashType struct {
+	Token token.Token // the '{' token
+	Key   TypeExpression
+	Value TypeExpression
+}
+
+func (ht *HashType) typeNode()            {}
+func (ht *HashType) TokenLiteral() string { return ht.Token.Literal }
+func (ht *HashType) String() string {
+	return "{" + ht.Key.String() + ": " + ht.Value.String() + "}"
+}
+
+// SetType is #{T}, a set of T
+type SetType struct {
+	Token   token.Token // the '#{' token
+	Element TypeExpression
+}
+
+func (st *SetType) typeNode()            {}
+func (st *SetType) TokenLiteral() string { return st.Token.Literal }
+func (st *SetType) String() string       { return "#{" + st.Element.String() + "}" }
+
+// FunctionType is fn(A, B) -> R, Return is nil without the arrow
+type FunctionType struct {
+	Token      token.Token // the 'fn' token
+	Parameters []TypeExpression
+	Return     TypeExpression
+}
+
+func (ft *FunctionType) typeNode()            {}
+func (ft *FunctionType) TokenLiteral() string { return ft.Token.Literal }
+func (ft *FunctionType) String() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range ft.Parameters {
+		params = append(params, p.String())
+	}
+	out.WriteString("fn(" + strings.Join(params, ", ") + ")")
+	if ft.Return != nil {
+		out.WriteString(" -> " + ft.Return.String())
+	}
+	return out.String()
+}
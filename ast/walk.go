@@ -0,0 +1,145 @@
+package ast
+
+import "fmt"
+
+// A Visitor's Visit is called by Walk for each node. If it returns a non-nil
+// visitor w, Walk visits the children of node with w, followed by a call of
+// w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the tree of node depth-first in source order, hash pairs
+// are visited as key and then value. Missing children, like the bounds of a
+// slice, and type annotations are skipped.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		walkStatements(v, n.Statements)
+
+	case *LetStatement:
+		Walk(v, n.Name)
+		walkExpression(v, n.Value)
+
+	case *ReturnStatement:
+		walkExpression(v, n.ReturnValue)
+
+	case *ExpressionStatement:
+		walkExpression(v, n.Expression)
+
+	case *BlockStatement:
+		walkStatements(v, n.Statements)
+
+	case *Identifier, *IntegerLiteral, *FloatLiteral, *StringLiteral, *Boolean:
+		// no children
+
+	case *PrefixExpression:
+		walkExpression(v, n.Right)
+
+	case *InfixExpression:
+		walkExpression(v, n.Left)
+		walkExpression(v, n.Right)
+
+	case *IfExpression:
+		walkExpression(v, n.Condition)
+		if n.Consequence != nil {
+			Walk(v, n.Consequence)
+		}
+		if n.Alternative != nil {
+			Walk(v, n.Alternative)
+		}
+
+	case *FunctionLiteral:
+		for _, param := range n.Parameters {
+			Walk(v, param)
+		}
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+
+	case *MacroLiteral:
+		for _, param := range n.Parameters {
+			Walk(v, param)
+		}
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+
+	case *CallExpression:
+		walkExpression(v, n.Function)
+		walkExpressions(v, n.Arguments)
+
+	case *ArrayLiteral:
+		walkExpressions(v, n.Elements)
+
+	case *SetLiteral:
+		walkExpressions(v, n.Elements)
+
+	case *IndexExpression:
+		walkExpression(v, n.Left)
+		walkExpression(v, n.Index)
+
+	case *SliceExpression:
+		walkExpression(v, n.Left)
+		walkExpression(v, n.Start)
+		walkExpression(v, n.End)
+		walkExpression(v, n.Step)
+
+	case *HashLiteral:
+		for _, key := range n.Keys {
+			Walk(v, key)
+			walkExpression(v, n.Pairs[key])
+		}
+
+	case *InterpolatedString:
+		walkExpressions(v, n.Expressions)
+
+	case *ImportExpression:
+		walkExpression(v, n.Path)
+
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+func walkStatements(v Visitor, stmts []Statement) {
+	for _, s := range stmts {
+		if s != nil {
+			Walk(v, s)
+		}
+	}
+}
+
+func walkExpressions(v Visitor, exps []Expression) {
+	for _, e := range exps {
+		walkExpression(v, e)
+	}
+}
+
+func walkExpression(v Visitor, e Expression) {
+	if e != nil {
+		Walk(v, e)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the tree of node like Walk, calling f for each node.
+// The children of node are visited if f returns true, after them f is
+// called with nil.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
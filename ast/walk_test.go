@@ -0,0 +1,160 @@
+package ast_test
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"monkey/token"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program, err := p.ParseProgram()
+	if err != nil {
+		t.Fatalf("parse error: %s", err)
+	}
+	return program
+}
+
+func TestInspect(t *testing.T) {
+	input := `let f = fn(a, b) { return -a + b[1:] };
+if (x in #{1.5}) { f(1, "s${y}") } else { {"k": [true]}[z] };
+import "m"`
+
+	expected := []string{
+		"*ast.Program",
+		"*ast.LetStatement", "*ast.Identifier f",
+		"*ast.FunctionLiteral", "*ast.Identifier a", "*ast.Identifier b",
+		"*ast.BlockStatement", "*ast.ReturnStatement",
+		"*ast.InfixExpression", "*ast.PrefixExpression", "*ast.Identifier a",
+		"*ast.SliceExpression", "*ast.Identifier b", "*ast.IntegerLiteral 1",
+		"*ast.ExpressionStatement", "*ast.IfExpression",
+		"*ast.InfixExpression", "*ast.Identifier x", "*ast.SetLiteral", "*ast.FloatLiteral 1.5",
+		"*ast.BlockStatement", "*ast.ExpressionStatement", "*ast.CallExpression",
+		"*ast.Identifier f", "*ast.IntegerLiteral 1",
+		"*ast.InterpolatedString", "*ast.Identifier y",
+		"*ast.BlockStatement", "*ast.ExpressionStatement", "*ast.IndexExpression",
+		"*ast.HashLiteral", "*ast.StringLiteral k", "*ast.ArrayLiteral", "*ast.Boolean true",
+		"*ast.Identifier z",
+		"*ast.ExpressionStatement", "*ast.ImportExpression", "*ast.StringLiteral m",
+	}
+
+	visited := []string{}
+	ends := 0
+	ast.Inspect(parse(t, input), func(node ast.Node) bool {
+		switch n := node.(type) {
+		case nil:
+			ends++
+		case *ast.Identifier, *ast.IntegerLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.Boolean:
+			visited = append(visited, fmt.Sprintf("%T %s", n, n.TokenLiteral()))
+		default:
+			visited = append(visited, fmt.Sprintf("%T", n))
+		}
+		return true
+	})
+
+	if strings.Join(visited, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("wrong nodes visited.\nwant=%q\ngot= %q", expected, visited)
+	}
+	if ends != len(expected) {
+		t.Errorf("wrong number of nil calls. want=%d, got=%d", len(expected), ends)
+	}
+}
+
+func TestInspectSkipsChildren(t *testing.T) {
+	program := parse(t, "let a = fn(x) { x + 1 }; a(2)")
+
+	idents := []string{}
+	ast.Inspect(program, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Identifier); ok {
+			idents = append(idents, ident.Value)
+		}
+		_, ok := node.(*ast.FunctionLiteral)
+		return !ok
+	})
+
+	if strings.Join(idents, ",") != "a,a" {
+		t.Errorf("wrong identifiers. got=%q", idents)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	input := `let f = fn(a) { if (1) { return 2 } else { [3, #{4}][a:5] } };
+{1: 2, "x": f(3)}; "${1}" + -4`
+	expected := `let f = fn <f>(a){if 2 {return 4;} else {[6,#{8}][a:10]}};` +
+		`{2:4, x:f(6)}("${2}" + (-8))`
+
+	program := parse(t, input)
+	double := func(node ast.Node) ast.Node {
+		if il, ok := node.(*ast.IntegerLiteral); ok {
+			il.Value *= 2
+			il.Token.Literal = fmt.Sprint(il.Value)
+		}
+		return node
+	}
+	result := ast.Rewrite(program, double)
+
+	if result != program {
+		t.Fatalf("result is not the program. got=%T", result)
+	}
+	if program.String() != expected {
+		t.Fatalf("wrong result.\nwant=%q\ngot= %q", expected, program.String())
+	}
+
+	hash := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.HashLiteral)
+	for _, key := range hash.Keys {
+		if _, ok := hash.Pairs[key]; !ok {
+			t.Errorf("key %s lost its value", key)
+		}
+	}
+}
+
+func TestRewriteReplace(t *testing.T) {
+	program := parse(t, "let a = b + c; fn(b) { b }")
+
+	// the parameter b can't be replaced by an integer
+	ast.Rewrite(program, func(node ast.Node) ast.Node {
+		if ident, ok := node.(*ast.Identifier); ok && ident.Value == "b" {
+			return &ast.IntegerLiteral{Token: token.Token{Type: token.INT, Literal: "1"}, Value: 1}
+		}
+		return node
+	})
+
+	expected := "let a = (1 + c);fn (b){1}"
+	if program.String() != expected {
+		t.Fatalf("wrong result. want=%q, got=%q", expected, program.String())
+	}
+}
+
+func TestCopy(t *testing.T) {
+	input := `let f = macro(a) { if (a) { [1, #{2}][0:1] } else { {"k": fn(x) { -x }}["k"](import "m") } };
+"${f(1)}" + 2.5`
+
+	program := parse(t, input)
+	copied := ast.Copy(program).(*ast.Program)
+	if copied == program || copied.String() != program.String() {
+		t.Fatalf("wrong copy. got=%q", copied.String())
+	}
+
+	// rewriting the copy leaves the original alone
+	ast.Rewrite(copied, func(node ast.Node) ast.Node {
+		if ident, ok := node.(*ast.Identifier); ok {
+			return &ast.Identifier{Token: ident.Token, Value: ident.Value + "2"}
+		}
+		if il, ok := node.(*ast.IntegerLiteral); ok {
+			il.Value = 9
+			il.Token.Literal = "9"
+		}
+		return node
+	})
+
+	if program.String() != parse(t, input).String() {
+		t.Errorf("original changed. got=%q", program.String())
+	}
+	if copied.String() == program.String() {
+		t.Errorf("copy not rewritten. got=%q", copied.String())
+	}
+}
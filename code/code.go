@@ -41,6 +41,11 @@ const (
 	OpClosure
 	OpGetFree
 	OpCurrentClosure
+	OpImport
+	OpSet
+	OpIn
+	OpSlice
+	OpConcat
 )
 
 type Definition struct {
@@ -81,6 +86,11 @@ var definitions = map[Opcode]*Definition{
 	OpClosure:        {"OpClosure", []int{2, 1}},
 	OpGetFree:        {"OpGetFree", []int{1}}, // argument: # of free variables on stack
 	OpCurrentClosure: {"OpCurrentClosure", []int{}},
+	OpImport:         {"OpImport", []int{}}, // import module of path on top of stack
+	OpSet:            {"OpSet", []int{2}},   // argument: the number of elements to make a set
+	OpIn:             {"OpIn", []int{}},
+	OpSlice:          {"OpSlice", []int{}},   // slice with start, end and step on top of stack
+	OpConcat:         {"OpConcat", []int{2}}, // argument: the number of values to join into a string
 }
 
 func Lookup(op byte) (*Definition, error) {
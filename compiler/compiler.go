@@ -6,7 +6,8 @@ import (
 	"monkey/ast"
 	"monkey/code"
 	"monkey/object"
-	"sort"
+	"monkey/token"
+	"strings"
 )
 
 type EmittedInstruction struct {
@@ -26,6 +27,30 @@ type Compiler struct {
 
 	scopes     []CompilationScope
 	scopeIndex int
+
+	warnings []string
+	errors   ErrorList
+}
+
+// Error is a semantic error at Pos, e.g. the use of an undefined variable
+type Error struct {
+	Pos token.Position
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// ErrorList is the error returned by Compile
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	msgs := make([]string, len(l))
+	for i, err := range l {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
 }
 
 func New() *Compiler {
@@ -82,34 +107,33 @@ func (c *Compiler) leaveScope() code.Instructions {
 	return instructions
 }
 
+// Compile compiles node. It goes on after an error, so that all of them are
+// found, and returns them as an ErrorList.
 func (c *Compiler) Compile(node ast.Node) error {
+	c.errors = nil
+	c.compile(node)
+	if len(c.errors) > 0 {
+		return c.errors
+	}
+	return nil
+}
+
+func (c *Compiler) compile(node ast.Node) {
 	switch node := node.(type) {
 	case *ast.Program:
 		for _, s := range node.Statements {
-			err := c.Compile(s)
-			if err != nil {
-				return err
-			}
+			c.compile(s)
 		}
 	case *ast.ExpressionStatement:
-		err := c.Compile(node.Expression)
-		if err != nil {
-			return err
-		}
+		c.compile(node.Expression)
 		c.emit(code.OpPop)
 	case *ast.BlockStatement:
 		for _, s := range node.Statements {
-			err := c.Compile(s)
-			if err != nil {
-				return err
-			}
+			c.compile(s)
 		}
 	case *ast.LetStatement:
-		symbol := c.symbolTable.Define(node.Name.Value)
-		err := c.Compile(node.Value)
-		if err != nil {
-			return err
-		}
+		symbol := c.defineBinding(node)
+		c.compile(node.Value)
 
 		if symbol.Scope == GlobalScope {
 			c.emit(code.OpSetGlobal, symbol.Index)
@@ -117,10 +141,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 			c.emit(code.OpSetLocal, symbol.Index)
 		}
 	case *ast.PrefixExpression:
-		err := c.Compile(node.Right)
-		if err != nil {
-			return err
-		}
+		c.compile(node.Right)
 		if node.Operator == "-" {
 			c.emit(code.OpMinus)
 		} else {
@@ -134,14 +155,8 @@ func (c *Compiler) Compile(node ast.Node) error {
 			right = node.Left
 		}
 
-		err := c.Compile(left)
-		if err != nil {
-			return err
-		}
-		err = c.Compile(right)
-		if err != nil {
-			return err
-		}
+		c.compile(left)
+		c.compile(right)
 		switch node.Operator {
 		case "+":
 			c.emit(code.OpAdd)
@@ -159,21 +174,17 @@ func (c *Compiler) Compile(node ast.Node) error {
 			c.emit(code.OpEqual)
 		case "!=":
 			c.emit(code.OpNotEqual)
+		case "in":
+			c.emit(code.OpIn)
 		default:
-			return fmt.Errorf("unknown operator %s", node.Operator)
+			c.errorf(node.Token.Pos, "unknown operator %s", node.Operator)
 		}
 	case *ast.IfExpression:
-		err := c.Compile(node.Condition)
-		if err != nil {
-			return err
-		}
+		c.compile(node.Condition)
 		// Emit an `OpJumpNotTruthy` with a bogus value
 		jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
 
-		err = c.Compile(node.Consequence)
-		if err != nil {
-			return err
-		}
+		c.compile(node.Consequence)
 
 		if c.lastInstructionIs(code.OpPop) {
 			c.removeLastPop()
@@ -188,10 +199,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		if node.Alternative == nil {
 			c.emit(code.OpNull)
 		} else {
-			err := c.Compile(node.Alternative)
-			if err != nil {
-				return err
-			}
+			c.compile(node.Alternative)
 			if c.lastInstructionIs(code.OpPop) {
 				c.removeLastPop()
 			}
@@ -201,6 +209,9 @@ func (c *Compiler) Compile(node ast.Node) error {
 	case *ast.IntegerLiteral:
 		integer := &object.Integer{Value: node.Value}
 		c.emit(code.OpConstant, c.addConstant(integer))
+	case *ast.FloatLiteral:
+		float := &object.Float{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(float))
 	case *ast.Boolean:
 		if node.Value {
 			c.emit(code.OpTrue)
@@ -213,48 +224,57 @@ func (c *Compiler) Compile(node ast.Node) error {
 	case *ast.Identifier:
 		symbol, ok := c.symbolTable.Resolve(node.Value)
 		if !ok {
-			return fmt.Errorf("undefined variable %s", node.Value)
+			c.undefined(node)
+			return
 		}
 		c.loadSymbol(symbol)
 	case *ast.ArrayLiteral:
 		for _, el := range node.Elements {
-			err := c.Compile(el)
-			if err != nil {
-				return err
-			}
+			c.compile(el)
 		}
 		c.emit(code.OpArray, len(node.Elements))
-	case *ast.HashLiteral:
-		// build sorted keys to have consistent bytecode for all elements
-		keys := []ast.Expression{}
-		for k := range node.Pairs {
-			keys = append(keys, k)
-		}
-		sort.Slice(keys, func(i, j int) bool {
-			return keys[i].String() < keys[j].String()
-		})
-
-		for _, k := range keys {
-			err := c.Compile(k)
-			if err != nil {
-				return err
+	case *ast.InterpolatedString:
+		// empty strings are left out, they add nothing
+		parts := 0
+		for i, s := range node.Strings {
+			if s != "" {
+				str := &object.String{Value: s}
+				c.emit(code.OpConstant, c.addConstant(str))
+				parts++
 			}
-			err = c.Compile(node.Pairs[k])
-			if err != nil {
-				return err
+			if i < len(node.Expressions) {
+				c.compile(node.Expressions[i])
+				parts++
 			}
 		}
-		c.emit(code.OpHash, len(node.Pairs)*2)
-	case *ast.IndexExpression:
-		err := c.Compile(node.Left)
-		if err != nil {
-			return err
+		c.emit(code.OpConcat, parts)
+	case *ast.SetLiteral:
+		for _, el := range node.Elements {
+			c.compile(el)
 		}
-		err = c.Compile(node.Index)
-		if err != nil {
-			return err
+		c.emit(code.OpSet, len(node.Elements))
+	case *ast.HashLiteral:
+		// keys in source order, so the hash keeps them in that order
+		for _, k := range node.Keys {
+			c.compile(k)
+			c.compile(node.Pairs[k])
 		}
+		c.emit(code.OpHash, len(node.Pairs)*2)
+	case *ast.IndexExpression:
+		c.compile(node.Left)
+		c.compile(node.Index)
 		c.emit(code.OpIndex)
+	case *ast.SliceExpression:
+		c.compile(node.Left)
+		// missing bounds are passed as null
+		for _, bound := range []ast.Expression{node.Start, node.End, node.Step} {
+			if bound == nil {
+				c.emit(code.OpNull)
+				continue
+			}
+			c.compile(bound)
+		}
+		c.emit(code.OpSlice)
 	case *ast.FunctionLiteral:
 		c.enterScope()
 
@@ -264,13 +284,13 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 		// put arguments into local bindings
 		for _, p := range node.Parameters {
+			if _, ok := c.symbolTable.ResolveLocal(p.Value); ok {
+				c.errorf(p.Token.Pos, "duplicate parameter %s", p.Value)
+			}
 			c.symbolTable.Define(p.Value)
 		}
 
-		err := c.Compile(node.Body)
-		if err != nil {
-			return err
-		}
+		c.compile(node.Body)
 		if c.lastInstructionIs(code.OpPop) {
 			c.replaceLastPopWithReturn()
 		}
@@ -295,26 +315,66 @@ func (c *Compiler) Compile(node ast.Node) error {
 		fnIndex := c.addConstant(compiledFn)
 		c.emit(code.OpClosure, fnIndex, len(freeSymbols))
 	case *ast.ReturnStatement:
-		err := c.Compile(node.ReturnValue)
-		if err != nil {
-			return err
-		}
+		c.compile(node.ReturnValue)
 		c.emit(code.OpReturnValue)
 	case *ast.CallExpression:
-		err := c.Compile(node.Function)
-		if err != nil {
-			return err
-		}
+		c.compile(node.Function)
 
 		for _, arg := range node.Arguments {
-			err := c.Compile(arg)
-			if err != nil {
-				return err
-			}
+			c.compile(arg)
 		}
 		c.emit(code.OpCall, len(node.Arguments))
+	case *ast.ImportExpression:
+		c.compile(node.Path)
+		c.emit(code.OpImport)
+	case *ast.MacroLiteral:
+		c.errorf(node.Token.Pos, "macro literal must be bound by a top-level let")
 	}
-	return nil
+}
+
+// defineBinding defines the name of a let/const statement in current scope,
+// rejecting any attempt to bind a constant name again, which leaves the
+// constant as it is
+func (c *Compiler) defineBinding(node *ast.LetStatement) Symbol {
+	name := node.Name.Value
+
+	prev, ok := c.symbolTable.ResolveLocal(name)
+	if ok && (prev.Scope == GlobalScope || prev.Scope == LocalScope) {
+		if prev.Constant {
+			c.errorf(node.Name.Token.Pos, "cannot redeclare constant %s", name)
+			return prev
+		}
+		if node.IsConst() {
+			c.errorf(node.Name.Token.Pos, "cannot declare constant %s, already declared in this scope", name)
+			return prev
+		}
+		c.warnings = append(c.warnings,
+			fmt.Sprintf("%s redeclared in the same scope", name))
+	}
+
+	if node.IsConst() {
+		return c.symbolTable.DefineConstant(name)
+	}
+	return c.symbolTable.Define(name)
+}
+
+// undefined reports the use of an undefined name, suggesting the visible
+// name closest to it
+func (c *Compiler) undefined(ident *ast.Identifier) {
+	if name := suggest(ident.Value, c.symbolTable.Names()); name != "" {
+		c.errorf(ident.Token.Pos, "undefined variable %s, did you mean `%s`?", ident.Value, name)
+		return
+	}
+	c.errorf(ident.Token.Pos, "undefined variable %s", ident.Value)
+}
+
+func (c *Compiler) errorf(pos token.Position, format string, a ...interface{}) {
+	c.errors = append(c.errors, &Error{Pos: pos, Msg: fmt.Sprintf(format, a...)})
+}
+
+// Warnings returns the non-fatal problems found during compilation
+func (c *Compiler) Warnings() []string {
+	return c.warnings
 }
 
 func (c *Compiler) addConstant(obj object.Object) int {
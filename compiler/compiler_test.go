@@ -81,6 +81,36 @@ func TestIntegerArithmetic(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func TestNumberLiterals(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "0x1f + 0o17 + 0b101 + 1_000",
+			expectedConstants: []interface{}{31, 15, 5, 1000},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpAdd),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpAdd),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "1.5 * 2e-3",
+			expectedConstants: []interface{}{1.5, 0.002},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpMul),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
 func TestBooleanExpressions(t *testing.T) {
 	tests := []compilerTestCase{
 		{
@@ -196,6 +226,34 @@ func TestStringExpressions(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func TestInterpolatedStrings(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `"a${1}b"`,
+			expectedConstants: []interface{}{"a", 1, "b"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpConcat, 3),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             `"${1 + 2}"`,
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpConcat, 1),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
 func TestConditionals(t *testing.T) {
 	tests := []compilerTestCase{
 		{
@@ -298,6 +356,159 @@ func TestGlobalLetStatements(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func TestConstStatements(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `
+			const one = 1;
+			one;
+			`,
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `
+			let one = 1;
+			let one = one + 1;
+			`,
+			expectedConstants: []interface{}{1, 1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpSetGlobal, 0),
+			},
+		},
+		{
+			input: `
+			const one = 1;
+			fn() { let one = 2; one };
+			`,
+			expectedConstants: []interface{}{
+				1,
+				2,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpClosure, 2, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestConstRebindingErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"const a = 1; let a = 2;", "1:18: cannot redeclare constant a"},
+		{"const a = 1; const a = 2;", "1:20: cannot redeclare constant a"},
+		{"let a = 1; const a = 2;", "1:18: cannot declare constant a, already declared in this scope"},
+		{"fn() { const a = 1; let a = 2; }", "1:25: cannot redeclare constant a"},
+		{"fn(a, a) { a }", "1:7: duplicate parameter a"},
+	}
+
+	for _, tt := range tests {
+		compiler := New()
+		err := compiler.Compile(parse(tt.input))
+		if err == nil {
+			t.Errorf("expected compiler error for %q", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error for %q. want=%q, got=%q", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestUndefinedVariables(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"x", []string{"1:1: undefined variable x"}},
+		{"let count = 1; cnt + 1", []string{"1:16: undefined variable cnt, did you mean `count`?"}},
+		{"lenn([1])", []string{"1:1: undefined variable lenn, did you mean `len`?"}},
+		{"let index = 0; let f = fn(items) { [idx, itme] }", []string{
+			"1:37: undefined variable idx, did you mean `index`?",
+			"1:42: undefined variable itme, did you mean `items`?",
+		}},
+		{"let f = fn(items) { itme }", []string{"1:21: undefined variable itme, did you mean `items`?"}},
+		{"let f = fn() { fo(1) }; let g = fn() { g(y) }", []string{
+			"1:16: undefined variable fo, did you mean `f`?",
+			"1:42: undefined variable y",
+		}},
+		{
+			"let total = 0;\nlet a = totl + b;\nfn(a, a) { a };\nconst c = 1; let c = 2",
+			[]string{
+				"2:9: undefined variable totl, did you mean `total`?",
+				"2:16: undefined variable b",
+				"3:7: duplicate parameter a",
+				"4:18: cannot redeclare constant c",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		compiler := New()
+		err := compiler.Compile(parse(tt.input))
+		errors, ok := err.(ErrorList)
+		if !ok {
+			t.Errorf("%q: expected ErrorList, got=%T (%v)", tt.input, err, err)
+			continue
+		}
+		if len(errors) != len(tt.expected) {
+			t.Errorf("%q: wrong number of errors. want=%d, got=%d (%v)",
+				tt.input, len(tt.expected), len(errors), errors)
+			continue
+		}
+		for i, e := range errors {
+			if e.Error() != tt.expected[i] {
+				t.Errorf("%q: error %d wrong. want=%q, got=%q", tt.input, i, tt.expected[i], e)
+			}
+		}
+	}
+}
+
+func TestLetRedeclarationWarning(t *testing.T) {
+	compiler := New()
+	err := compiler.Compile(parse("let a = 1; let a = 2; fn() { let b = 1; let b = 2; }"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	expected := []string{
+		"a redeclared in the same scope",
+		"b redeclared in the same scope",
+	}
+	warnings := compiler.Warnings()
+	if len(warnings) != len(expected) {
+		t.Fatalf("wrong number of warnings. want=%d, got=%d (%q)",
+			len(expected), len(warnings), warnings)
+	}
+	for i, w := range expected {
+		if warnings[i] != w {
+			t.Errorf("warning %d wrong. want=%q, got=%q", i, w, warnings[i])
+		}
+	}
+}
+
 func TestLetStatementScopes(t *testing.T) {
 	tests := []compilerTestCase{
 		{
@@ -412,6 +623,43 @@ func TestArrayLiterals(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func TestSetLiterals(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `#{}`,
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpSet, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             `#{1, 2 + 3}`,
+			expectedConstants: []interface{}{1, 2, 3},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpAdd),
+				code.Make(code.OpSet, 2),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             `1 in #{2}`,
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSet, 1),
+				code.Make(code.OpIn),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
 func TestHashLiterals(t *testing.T) {
 	tests := []compilerTestCase{
 		{
@@ -491,6 +739,39 @@ func TestIndexExpression(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func TestSliceExpression(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "[1, 2][1:]",
+			expectedConstants: []interface{}{1, 2, 1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpArray, 2),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpNull),
+				code.Make(code.OpNull),
+				code.Make(code.OpSlice),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             `"abc"[:-1:2]`,
+			expectedConstants: []interface{}{"abc", 1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpNull),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpMinus),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpSlice),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
 func TestFunctions(t *testing.T) {
 	tests := []compilerTestCase{
 		{
@@ -970,6 +1251,23 @@ func TestRecursiveFunctions(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func TestImportExpression(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `import "math"["add"]`,
+			expectedConstants: []interface{}{"math", "add"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpImport),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpIndex),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
 func runCompilerTests(t *testing.T, tests []compilerTestCase) {
 	t.Helper()
 
@@ -999,7 +1297,8 @@ func runCompilerTests(t *testing.T, tests []compilerTestCase) {
 func parse(input string) *ast.Program {
 	l := lexer.New(input)
 	p := parser.New(l)
-	return p.ParseProgram()
+	program, _ := p.ParseProgram()
+	return program
 }
 
 func testInstructions(expected []code.Instructions, actual code.Instructions) error {
@@ -1038,6 +1337,11 @@ func testConstants(t *testing.T, expected []interface{}, actual []object.Object)
 			if err != nil {
 				return fmt.Errorf("constant %d - testIntegerObject failed: %s", i, err)
 			}
+		case float64:
+			err := testFloatObject(constant, actual[i])
+			if err != nil {
+				return fmt.Errorf("constant %d - testFloatObject failed: %s", i, err)
+			}
 		case string:
 			err := testStringObject(constant, actual[i])
 			if err != nil {
@@ -1069,6 +1373,18 @@ func testIntegerObject(expected int64, actual object.Object) error {
 	return nil
 }
 
+func testFloatObject(expected float64, actual object.Object) error {
+	result, ok := actual.(*object.Float)
+	if !ok {
+		return fmt.Errorf("object is not float. got=%T (%+v)", actual, actual)
+	}
+
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value. got=%g, want=%g", result.Value, expected)
+	}
+	return nil
+}
+
 func testStringObject(expected string, actual object.Object) error {
 	result, ok := actual.(*object.String)
 	if !ok {
@@ -0,0 +1,78 @@
+package compiler
+
+// suggest returns the one of names closest to the misspelled name, or "" if
+// none is close enough to be what was meant. Names which name abbreviates,
+// like count for cnt, win over those a typo away.
+func suggest(name string, names []string) string {
+	best, bestAbbrev, bestDistance := "", false, 0
+	for _, candidate := range names {
+		d := distance(name, candidate)
+		abbrev := abbreviates(name, candidate)
+		if d == 0 || !abbrev && !closeEnough(name, d) {
+			continue
+		}
+		if best == "" || abbrev && !bestAbbrev || abbrev == bestAbbrev && d < bestDistance {
+			best, bestAbbrev, bestDistance = candidate, abbrev, d
+		}
+	}
+	return best
+}
+
+// abbreviates tells whether name is a shorter form of candidate, with the
+// same first character and the rest of its characters in order
+func abbreviates(name, candidate string) bool {
+	s, t := []rune(name), []rune(candidate)
+	if len(s) < 3 || len(s) >= len(t) || s[0] != t[0] {
+		return false
+	}
+	i := 1
+	for _, r := range t[1:] {
+		if i < len(s) && s[i] == r {
+			i++
+		}
+	}
+	return i == len(s)
+}
+
+// closeEnough tells whether a name d edits away from name is a likely typo,
+// longer names may have more of them
+func closeEnough(name string, d int) bool {
+	n := len([]rune(name))
+	most := n / 3
+	if most < 2 {
+		most = 2
+	}
+	return d <= most && d < n
+}
+
+// distance returns the Levenshtein distance of a and b in characters
+func distance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	prev := make([]int, len(t)+1)
+	cur := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		cur[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(t)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -1,5 +1,7 @@
 package compiler
 
+import "sort"
+
 type SymbolScope string
 
 const (
@@ -11,9 +13,10 @@ const (
 )
 
 type Symbol struct {
-	Name  string
-	Scope SymbolScope
-	Index int
+	Name     string
+	Scope    SymbolScope
+	Index    int
+	Constant bool // declared with `const`, can't be bound again
 }
 
 type SymbolTable struct {
@@ -38,20 +41,67 @@ func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
 }
 
 func (st *SymbolTable) Define(name string) Symbol {
+	return st.define(name, false)
+}
+
+func (st *SymbolTable) DefineConstant(name string) Symbol {
+	return st.define(name, true)
+}
+
+func (st *SymbolTable) define(name string, constant bool) Symbol {
 	s := Symbol{
-		Name:  name,
-		Index: st.numDefinitions,
+		Name:     name,
+		Index:    st.numDefinitions,
+		Constant: constant,
 	}
 	if st.Outer == nil {
 		s.Scope = GlobalScope
 	} else {
 		s.Scope = LocalScope
 	}
+
+	// binding the same name again in this scope reuses its slot
+	if prev, ok := st.store[name]; ok && prev.Scope == s.Scope {
+		s.Index = prev.Index
+	} else {
+		st.numDefinitions++
+	}
 	st.store[name] = s
-	st.numDefinitions++
 	return s
 }
 
+// Definitions returns the global or local bindings defined in this table,
+// ordered by their index
+func (st *SymbolTable) Definitions() []Symbol {
+	symbols := []Symbol{}
+	for _, s := range st.store {
+		if s.Scope == GlobalScope || s.Scope == LocalScope {
+			symbols = append(symbols, s)
+		}
+	}
+	sort.Slice(symbols, func(i, j int) bool {
+		return symbols[i].Index < symbols[j].Index
+	})
+	return symbols
+}
+
+// Names returns the sorted names which can be resolved in this table,
+// including those of enclosing scopes and builtins
+func (st *SymbolTable) Names() []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for ; st != nil; st = st.Outer {
+		for name := range st.store {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
 func (st *SymbolTable) DefineBuiltin(index int, name string) Symbol {
 	symbol := Symbol{Name: name, Index: index, Scope: BuiltinScope}
 	st.store[name] = symbol
@@ -73,6 +123,13 @@ func (st *SymbolTable) DefineFunctionName(name string) Symbol {
 	return symbol
 }
 
+// ResolveLocal looks up name in this table only, without walking into
+// enclosing scopes
+func (st *SymbolTable) ResolveLocal(name string) (Symbol, bool) {
+	obj, ok := st.store[name]
+	return obj, ok
+}
+
 func (st *SymbolTable) Resolve(name string) (Symbol, bool) {
 	obj, ok := st.store[name]
 	if !ok && st.Outer != nil {
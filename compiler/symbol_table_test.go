@@ -182,3 +182,76 @@ func TestShadowingFunctionName(t *testing.T) {
 		t.Errorf("expected %s to resolve to %+v, got=%+v", expected.Name, expected, result)
 	}
 }
+
+func TestRedefineInSameScope(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+	global.Define("b")
+
+	expected := Symbol{Name: "a", Scope: GlobalScope, Index: 0}
+	result := global.Define("a")
+	if result != expected {
+		t.Errorf("expected redefined a=%+v, got=%+v", expected, result)
+	}
+
+	c := global.Define("c")
+	if c.Index != 2 {
+		t.Errorf("expected c to get next free index 2, got=%d", c.Index)
+	}
+
+	local := NewEnclosedSymbolTable(global)
+	shadow := local.Define("a")
+	expected = Symbol{Name: "a", Scope: LocalScope, Index: 0}
+	if shadow != expected {
+		t.Errorf("expected shadowing a=%+v, got=%+v", expected, shadow)
+	}
+}
+
+func TestDefineConstant(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineConstant("a")
+	global.Define("b")
+
+	expected := []Symbol{
+		{Name: "a", Scope: GlobalScope, Index: 0, Constant: true},
+		{Name: "b", Scope: GlobalScope, Index: 1},
+	}
+
+	local := NewEnclosedSymbolTable(global)
+	for _, sym := range expected {
+		result, ok := local.Resolve(sym.Name)
+		if !ok {
+			t.Errorf("name %s not resolvable", sym.Name)
+			continue
+		}
+		if result != sym {
+			t.Errorf("expected %s to resolve to %+v, got=%+v", sym.Name, sym, result)
+		}
+	}
+
+	if _, ok := local.ResolveLocal("a"); ok {
+		t.Errorf("ResolveLocal should not see names of enclosing scope")
+	}
+}
+
+func TestNames(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineBuiltin(0, "len")
+	global.Define("b")
+
+	local := NewEnclosedSymbolTable(global)
+	local.DefineFunctionName("f")
+	local.Define("a")
+	local.Define("b")
+
+	expected := []string{"a", "b", "f", "len"}
+	names := local.Names()
+	if len(names) != len(expected) {
+		t.Fatalf("wrong names. want=%v, got=%v", expected, names)
+	}
+	for i, name := range expected {
+		if names[i] != name {
+			t.Errorf("wrong name %d. want=%q, got=%q", i, name, names[i])
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around changes
+const diffContext = 3
+
+type diffLine struct {
+	kind         byte // ' ', '-' or '+'
+	text         string
+	oldNo, newNo int // of the lines before it
+}
+
+// diff returns a unified diff turning a into b
+func diff(name string, a, b []byte) string {
+	lines := diffLines(splitLines(string(a)), splitLines(string(b)))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s.orig\n+++ %s\n", name, name)
+	for start := 0; start < len(lines); {
+		if lines[start].kind == ' ' {
+			start++
+			continue
+		}
+
+		// a hunk ends with more unchanged lines than two contexts in a row
+		end := start
+		for end < len(lines) {
+			if lines[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(lines) && lines[run].kind == ' ' {
+				run++
+			}
+			if run == len(lines) || run-end > 2*diffContext {
+				end += diffContext
+				if end > len(lines) {
+					end = len(lines)
+				}
+				break
+			}
+			end = run
+		}
+		from := start - diffContext
+		if from < 0 {
+			from = 0
+		}
+
+		hunk := lines[from:end]
+		oldCount, newCount := 0, 0
+		for _, l := range hunk {
+			if l.kind != '+' {
+				oldCount++
+			}
+			if l.kind != '-' {
+				newCount++
+			}
+		}
+		oldStart, newStart := hunk[0].oldNo, hunk[0].newNo
+		if oldCount > 0 {
+			oldStart++
+		}
+		if newCount > 0 {
+			newStart++
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+		for _, l := range hunk {
+			out.WriteByte(l.kind)
+			out.WriteString(l.text + "\n")
+		}
+		start = end
+	}
+	return out.String()
+}
+
+// diffLines merges x and y along their longest common subsequence
+func diffLines(x, y []string) []diffLine {
+	// lcs[i][j] is the length of the longest common subsequence of x[i:]
+	// and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	lines := []diffLine{}
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			lines = append(lines, diffLine{' ', x[i], i, j})
+			i++
+			j++
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{'-', x[i], i, j})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', y[j], i, j})
+			j++
+		}
+	}
+	return lines
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
@@ -4,8 +4,11 @@ import (
 	"monkey/object"
 )
 
-var builtins = map[string]*object.Builtin{
-	"len":  object.GetBuiltinByName("len"),
-	"push": object.GetBuiltinByName("push"),
-	"puts": object.GetBuiltinByName("puts"),
+// builtins are the same as the ones of the VM
+var builtins = map[string]*object.Builtin{}
+
+func init() {
+	for _, def := range object.Builtins {
+		builtins[def.Name] = def.Builtin
+	}
 }
@@ -4,12 +4,13 @@ import (
 	"fmt"
 	"monkey/ast"
 	"monkey/object"
+	"strings"
 )
 
 var (
-	NULL  = &object.Null{}
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
+	NULL  = object.NULL
+	TRUE  = object.TRUE
+	FALSE = object.FALSE
 )
 
 func Eval(node ast.Node, env *object.Environment) object.Object {
@@ -31,6 +32,9 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if isError(right) {
 			return right
 		}
+		if node.Operator == "in" {
+			return evalInExpression(left, right)
+		}
 		return evalInfixExpression(node.Operator, left, right)
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
@@ -46,6 +50,8 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return &object.ReturnValue{Value: val}
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 	case *ast.Boolean:
@@ -53,16 +59,36 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
 	case *ast.LetStatement:
+		name := node.Name.Value
+		if env.IsConstant(name) {
+			return newError("cannot redeclare constant %s", name)
+		}
+		if node.IsConst() && env.Has(name) {
+			return newError("cannot declare constant %s, already declared in this scope", name)
+		}
 		val := Eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
-		env.Set(node.Name.Value, val)
+		if node.IsConst() {
+			env.SetConstant(name, val)
+		} else {
+			env.Set(name, val)
+		}
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
 		return &object.Function{Parameters: params, Env: env, Body: body}
+	case *ast.MacroLiteral:
+		return newError("macro literal must be bound by a top-level let")
 	case *ast.CallExpression:
+		if isCallOf(node, "quote") {
+			if len(node.Arguments) != 1 {
+				return newError("wrong number of arguments to quote: want=1, got=%d",
+					len(node.Arguments))
+			}
+			return quote(node.Arguments[0], env)
+		}
 		function := Eval(node.Function, env)
 		if isError(function) {
 			return function
@@ -88,8 +114,24 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return index
 		}
 		return evalIndexExpression(left, index)
+	case *ast.SliceExpression:
+		return evalSliceExpression(node, env)
 	case *ast.HashLiteral:
 		return evalHashLiteral(node, env)
+	case *ast.InterpolatedString:
+		return evalInterpolatedString(node, env)
+	case *ast.SetLiteral:
+		elements := evalExpressions(node.Elements, env)
+		if len(elements) == 1 && isError(elements[0]) {
+			return elements[0]
+		}
+		return evalSetLiteral(elements)
+	case *ast.ImportExpression:
+		path := Eval(node.Path, env)
+		if isError(path) {
+			return path
+		}
+		return evalImportExpression(path)
 	}
 
 	return nil
@@ -111,11 +153,21 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 func applyFunction(fn object.Object, args []object.Object) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
+		if len(args) != len(fn.Parameters) {
+			return newError("wrong number of arguments: want=%d, got=%d",
+				len(fn.Parameters), len(args))
+		}
 		extendedEnv := extendFunctionEnv(fn, args)
 		evaluated := Eval(fn.Body, extendedEnv)
 		return unwrapReturnValue(evaluated)
 	case *object.Builtin:
-		if result := fn.Fn(args...); result != nil {
+		var result object.Object
+		if fn.HigherOrderFn != nil {
+			result = fn.HigherOrderFn(callFunction, args...)
+		} else {
+			result = fn.Fn(args...)
+		}
+		if result != nil {
 			return result
 		}
 		return NULL
@@ -124,6 +176,15 @@ func applyFunction(fn object.Object, args []object.Object) object.Object {
 	}
 }
 
+// callFunction lets higher order builtins call back into monkey functions,
+// a body without a value, e.g. ending with a let, returns null
+func callFunction(fn object.Object, args ...object.Object) object.Object {
+	if result := applyFunction(fn, args); result != nil {
+		return result
+	}
+	return NULL
+}
+
 func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
 	env := object.NewEnclosedEnvironment(fn.Env)
 
@@ -239,6 +300,9 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 }
 
 func evalMinusOperatorExpression(right object.Object) object.Object {
+	if f, ok := right.(*object.Float); ok {
+		return &object.Float{Value: -f.Value}
+	}
 	if right.Type() != object.INTEGER_OBJ {
 		return NULL
 	}
@@ -253,6 +317,8 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 		return evalIntegerInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
+	case isNumber(left) && isNumber(right):
+		return evalFloatInfixExpression(operator, left, right)
 	case left.Type() != right.Type():
 		return newError("type mismatch: %s %s", left.Type(), right.Type())
 	default:
@@ -288,6 +354,39 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	}
 }
 
+// evalFloatInfixExpression computes with floats, integers mixed with floats
+// get converted
+func evalFloatInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal, _ := object.ToFloat(left)
+	rightVal, _ := object.ToFloat(right)
+
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		return &object.Float{Value: leftVal / rightVal}
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError("unknown operartor: %s %s %s",
+			left.Type(), operator, right.Type())
+	}
+}
+
+func isNumber(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
+}
+
 func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
 	leftVal := left.(*object.String).Value
 	rightValue := right.(*object.String).Value
@@ -319,8 +418,12 @@ func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalStringIndexExpression(left, index)
 	case left.Type() == object.HASH_OBJ:
 		return evalHashIndexExpression(left, index)
+	case left.Type() == object.MODULE_OBJ:
+		return evalHashIndexExpression(left.(*object.Module).Exports, index)
 	default:
 		return newError("index operator not supported: %s", left.Type())
 	}
@@ -331,6 +434,9 @@ func evalArrayIndexExpression(array, index object.Object) object.Object {
 	idx := index.(*object.Integer).Value
 	max := int64(len(arrayObject.Elements) - 1)
 
+	if idx < 0 {
+		idx += max + 1 // count from the end
+	}
 	if idx < 0 || idx > max {
 		return NULL
 	}
@@ -338,42 +444,119 @@ func evalArrayIndexExpression(array, index object.Object) object.Object {
 	return arrayObject.Elements[idx]
 }
 
+// evalStringIndexExpression indexes by characters, the result is a string of
+// one character
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	runes := []rune(str.(*object.String).Value)
+	idx := index.(*object.Integer).Value
+	max := int64(len(runes) - 1)
+
+	if idx < 0 {
+		idx += max + 1 // count from the end
+	}
+	if idx < 0 || idx > max {
+		return NULL
+	}
+
+	return &object.String{Value: string(runes[idx])}
+}
+
+func evalSliceExpression(node *ast.SliceExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	// missing bounds are passed as null
+	bounds := []object.Object{NULL, NULL, NULL}
+	for i, bound := range []ast.Expression{node.Start, node.End, node.Step} {
+		if bound == nil {
+			continue
+		}
+		bounds[i] = Eval(bound, env)
+		if isError(bounds[i]) {
+			return bounds[i]
+		}
+	}
+
+	sliced, err := object.Slice(left, bounds[0], bounds[1], bounds[2])
+	if err != nil {
+		return newError("%s", err)
+	}
+	return sliced
+}
+
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+	hash := object.NewHash()
 
-	for keyNode, valueNode := range node.Pairs {
+	for _, keyNode := range node.Keys {
 		key := Eval(keyNode, env)
 		if isError(key) {
 			return key
 		}
 
-		hashKey, ok := key.(object.Hashable)
+		hashKey, ok := object.AsHashable(key)
 		if !ok {
 			return newError("unusable as hash key: %s", key.Type())
 		}
 
-		value := Eval(valueNode, env)
+		value := Eval(node.Pairs[keyNode], env)
+		if isError(value) {
+			return value
+		}
+		hash.Set(hashKey, value)
+	}
+	return hash
+}
+
+func evalInterpolatedString(node *ast.InterpolatedString, env *object.Environment) object.Object {
+	var out strings.Builder
+
+	for i, e := range node.Expressions {
+		out.WriteString(node.Strings[i])
+		value := Eval(e, env)
 		if isError(value) {
 			return value
 		}
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		out.WriteString(value.Inspect())
+	}
+	out.WriteString(node.Strings[len(node.Strings)-1])
+	return &object.String{Value: out.String()}
+}
+
+func evalSetLiteral(elements []object.Object) object.Object {
+	set := object.NewSet()
+
+	for _, el := range elements {
+		e, ok := object.AsHashable(el)
+		if !ok {
+			return newError("unusable as set element: %s", el.Type())
+		}
+		set.Add(e)
+	}
+	return set
+}
+
+func evalInExpression(value, collection object.Object) object.Object {
+	ok, err := object.Contains(collection, value)
+	if err != nil {
+		return newError("%s", err)
 	}
-	return &object.Hash{Pairs: pairs}
+	return nativeBoolToBooleanObject(ok)
 }
 
 func evalHashIndexExpression(hash, index object.Object) object.Object {
 	hashObject := hash.(*object.Hash)
 
-	key, ok := index.(object.Hashable)
+	key, ok := object.AsHashable(index)
 	if !ok {
 		return newError("unusable as hash key: %s", index.Type())
 	}
 
-	pair, ok := hashObject.Pairs[key.HashKey()]
+	value, ok := hashObject.Get(key)
 	if !ok {
 		return NULL
 	}
 
-	return pair.Value
+	return value
 }
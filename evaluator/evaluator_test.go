@@ -0,0 +1,396 @@
+package evaluator
+
+import (
+	"monkey/lexer"
+	"monkey/module"
+	"monkey/object"
+	"monkey/parser"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type evalTestCase struct {
+	input    string
+	expected interface{}
+}
+
+func TestImport(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"math.monkey": `
+			let add = fn(a, b) { a + b };
+			let inc = fn(a) { add(a, 1) };
+			const two = 2;
+		`,
+		"lib/greet.monkey": `
+			let m = import "math";
+			let greet = fn(name) { "hello " + name };
+			let four = m["add"](m["two"], 2);
+		`,
+		"a.monkey": `let b = import "b";`,
+		"b.monkey": `let a = import "a";`,
+	}
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defer func(loader *module.Loader) { ModuleLoader = loader }(ModuleLoader)
+	ModuleLoader = NewModuleLoader(dir, filepath.Join(dir, "lib"))
+
+	tests := []evalTestCase{
+		{`let m = import "math"; m["add"](1, 2)`, 3},
+		{`import "math.monkey"["two"]`, 2},
+		{`let add = fn(a) { a }; import "math"["inc"](add(1))`, 2},
+		{`import "math"["missing"]`, NULL},
+		{`let g = import "greet"; g["greet"]("monkey")`, "hello monkey"},
+		{`import "greet"["four"]`, 4},
+		{`import "greet"["m"]["add"](1, 1)`, 2},
+	}
+	runEvalTests(t, tests)
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "nothing"`, "module \"nothing\" not found"},
+		{`import "a"`, "import cycle detected"},
+	}
+	for _, tt := range errorTests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Fatalf("expected error for %q", tt.input)
+		}
+		if !strings.Contains(errObj.Message, tt.expected) {
+			t.Errorf("wrong error for %q. want it to contain %q, got=%q",
+				tt.input, tt.expected, errObj.Message)
+		}
+	}
+}
+
+func TestStringBuiltins(t *testing.T) {
+	tests := []evalTestCase{
+		{`split("a,b,,c", ",")`, []string{"a", "b", "", "c"}},
+		{"split(\"  a b\tc \")", []string{"a", "b", "c"}},
+		{`split("héllo", "")`, []string{"h", "é", "l", "l", "o"}},
+		{`join(["a", "b", "c"], "-")`, "a-b-c"},
+		{`join([], "-")`, ""},
+		{`join([1], "-")`, &object.Error{Message: "elements to `join` must be STRING, got INTEGER"}},
+		{"trim(\"  hi \n\")", "hi"},
+		{`trim("xxhixx", "x")`, "hi"},
+		{`upper("éa")`, "ÉA"},
+		{`lower("ÀB")`, "àb"},
+		{`contains("monkey", "key")`, true},
+		{`contains("monkey", "donkey")`, false},
+		{`replace("a-b-c", "-", "+")`, "a+b+c"},
+		{`index_of("héllo", "l")`, 2},
+		{`index_of("hello", "z")`, -1},
+		{`substr("héllo", 1, 3)`, "éll"},
+		{`substr("héllo", 3)`, "lo"},
+		{`substr("héllo", 3, 99)`, "lo"},
+		{`substr("héllo", 9)`, ""},
+		{`substr("héllo", 1, -1)`, &object.Error{Message: "length to `substr` must not be negative, got -1"}},
+		{`starts_with("monkey", "mon")`, true},
+		{`ends_with("monkey", "mon")`, false},
+		{`format("%s is %d, %v", "x", 42, true)`, "x is 42, true"},
+		{`format("%s", [1, 2])`, "[1, 2]"},
+		{`str(42)`, "42"},
+		{`str([1, "a"])`, "[1, a]"},
+		{`int(" -7 ")`, -7},
+		{`int(true)`, 1},
+		{`int("4x")`, &object.Error{Message: "could not parse \"4x\" as integer"}},
+		{`upper(1)`, &object.Error{Message: "argument 1 to `upper` must be STRING, got INTEGER"}},
+		{`replace("a", "b")`, &object.Error{Message: "wrong number of arguments, got=2, want=3"}},
+		{`let shout = fn(s) { upper(s) + "!" }; shout(trim(" hi "))`, "HI!"},
+	}
+	runEvalTests(t, tests)
+}
+
+func TestCollectionBuiltins(t *testing.T) {
+	tests := []evalTestCase{
+		{`map([1, 2, 3], fn(x) { x * 2 })`, []int{2, 4, 6}},
+		{`map(["a", "b"], upper)`, []string{"A", "B"}},
+		{`let n = 10; map([1, 2], fn(x) { x + n })`, []int{11, 12}},
+		{`map([1, 2], fn(x, y) { x })`, &object.Error{Message: "wrong number of arguments: want=2, got=1"}},
+		{`map([1, 2], 1)`, &object.Error{Message: "argument 2 to `map` must be FUNCTION, got INTEGER"}},
+		{`str(map([1], fn(x) { let y = x; }))`, "[null]"},
+		{`filter([1, 2, 3, 4], fn(x) { x > 2 })`, []int{3, 4}},
+		{`filter([1, 2], fn(x) { let y = x; })`, []int{}},
+		{`filter([1, 2], fn(x) { x + "a" })`, &object.Error{Message: "type mismatch: INTEGER STRING"}},
+		{`reduce([1, 2, 3, 4], 0, fn(acc, x) { acc + x })`, 10},
+		{`reduce([], 5, fn(acc, x) { acc + x })`, 5},
+		{`reduce([1], 0, fn(acc, x) { return acc - x; })`, -1},
+		{`sort([3, 1, 2])`, []int{1, 2, 3}},
+		{`sort([3, 1, 2], fn(a, b) { a > b })`, []int{3, 2, 1}},
+		{`sort([3, 1, 2], fn(a, b) { b - a })`, []int{3, 2, 1}},
+		{`sort([1, 2], fn(a, b) { let x = 1; })`, &object.Error{Message: "comparator of `sort` must return BOOLEAN or INTEGER, got NULL"}},
+		{`sort([1, "a"])`, &object.Error{Message: "cannot compare STRING with INTEGER"}},
+		{`range(5, 0, -2)`, []int{5, 3, 1}},
+		{`range(0, 9223372036854775807, 4611686018427387904)`, []int{0, 4611686018427387904}},
+		{`
+		let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } };
+		map(range(8), fib)
+		`, []int{0, 1, 1, 2, 3, 5, 8, 13}},
+	}
+	runEvalTests(t, tests)
+}
+
+func TestFloatArithmetic(t *testing.T) {
+	tests := []evalTestCase{
+		{"1.5", 1.5},
+		{"1e3", 1000.0},
+		{"2.5e-1 * 4", 1.0},
+		{"1.5 + 1", 2.5},
+		{"1 + 1.5", 2.5},
+		{"3 - 0.5", 2.5},
+		{"0.5 * 3", 1.5},
+		{"3 / 2.0", 1.5},
+		{"3 / 2", 1},
+		{"-1.5", -1.5},
+		{"-(1 - 2.5)", 1.5},
+		{"1.5 < 2", true},
+		{"2 > 1.5", true},
+		{"1 == 1.0", true},
+		{"1 != 1.0", false},
+		{"0.1 + 0.2 == 0.3", false},
+		{"if (0.5 < 1) { 10 } else { 20 }", 10},
+		{`1.5 + "a"`, &object.Error{Message: "type mismatch: FLOAT STRING"}},
+	}
+	runEvalTests(t, tests)
+}
+
+func TestMathBuiltins(t *testing.T) {
+	tests := []evalTestCase{
+		{`abs(sqrt(4) * -1)`, 2.0},
+		{`max(1, sqrt(9))`, 3.0},
+		{`pow(2, 10)`, 1024},
+		{`pow(2, -1)`, 0.5},
+		{`pow(2, 64)`, 18446744073709551616.0},
+		{`sqrt(2) * sqrt(2) > 1`, true},
+		{`floor(mean([1, 2]))`, 1},
+		{`round(-1 * mean([1, 2]))`, -2},
+		{`round(pow(0, -1))`, &object.Error{Message: "argument to `round` must be finite, got +Inf"}},
+		{`sum([1, sqrt(4)])`, 3.0},
+		{`mean([1, 2]) + 1`, 2.5},
+		{`-mean([1, 2])`, -1.5},
+		{`mean([1, 2]) == mean([2, 1])`, true},
+		{`mean([1, 2]) < 2`, true},
+		{`rand_int(5, 6)`, 5},
+	}
+	runEvalTests(t, tests)
+}
+
+func TestHashInsertionOrder(t *testing.T) {
+	tests := []evalTestCase{
+		{`str({3: 1, 1: 2, 2: 3})`, "{3: 1, 1: 2, 2: 3}"},
+		{`str({"b": 1, "a": 2, "b": 3})`, "{b: 3, a: 2}"},
+		{`str({true: [], "x": {2: 0, 1: 0}})`, "{true: [], x: {2: 0, 1: 0}}"},
+		{`keys({"b": 1, "a": 2, "c": 3})`, []string{"b", "a", "c"}},
+		{`values({"b": 1, "a": 2, "b": 3})`, []int{3, 2}},
+		{`keys(delete({"c": 1, "a": 2, "b": 3}, "a"))`, []string{"c", "b"}},
+		{`let h = {"x": 1, "y": 2}; str({"y": 0, "x": h["x"]})`, "{y: 0, x: 1}"},
+		{`keys(json_parse(json_stringify({"b": 1, "a": 2})))`, []string{"a", "b"}},
+		{`{[1, "a"]: 1, [1, "b"]: 2}[[1, "b"]]`, 2},
+		{`{1: 1}[0]`, NULL},
+	}
+	runEvalTests(t, tests)
+}
+
+func TestSetLiterals(t *testing.T) {
+	tests := []evalTestCase{
+		{`str(#{})`, "#{}"},
+		{`str(#{3, 1, 3, 2, 1})`, "#{3, 1, 2}"},
+		{`str(#{[1, 2], [1, 2], "a", true})`, "#{[1, 2], a, true}"},
+		{`len(#{1, 1 + 0, 2})`, 2},
+		{`let s = #{1, 2}; 2 in s`, true},
+		{`3 in #{1, 2}`, false},
+		{`[1] in #{[1]}`, true},
+		{`"a" in {"a": 1}`, true},
+		{`"b" in {"a": 1}`, false},
+		{`2 in [1, 2, 3]`, true},
+		{`[2] in [[1], [2]]`, true},
+		{`4 in [1, 2, 3]`, false},
+		{`"ell" in "hello"`, true},
+		{`"x" in "hello"`, false},
+		{`if (1 in #{1}) { 10 } else { 20 }`, 10},
+		{`to_array(union(#{1, 2}, #{3, 2}))`, []int{1, 2, 3}},
+	}
+	runEvalTests(t, tests)
+}
+
+func TestSetErrors(t *testing.T) {
+	tests := []evalTestCase{
+		{`#{1, {}}`, &object.Error{Message: "unusable as set element: HASH"}},
+		{`{} in #{1}`, &object.Error{Message: "unusable as set element: HASH"}},
+		{`[{}] in {}`, &object.Error{Message: "unusable as hash key: ARRAY"}},
+		{`1 in "abc"`, &object.Error{Message: "type mismatch: INTEGER in STRING"}},
+		{`1 in 2`, &object.Error{Message: "unknown operator: INTEGER in INTEGER"}},
+		{`#{1, 1 + "a"}`, &object.Error{Message: "type mismatch: INTEGER STRING"}},
+		{`(1 + "a") in #{1}`, &object.Error{Message: "type mismatch: INTEGER STRING"}},
+	}
+	runEvalTests(t, tests)
+}
+
+func TestIndexExpression(t *testing.T) {
+	tests := []evalTestCase{
+		{"[1, 2, 3][1]", 2},
+		{"[1, 2, 3][99]", NULL},
+		{"[1][-1]", 1},
+		{"[1, 2, 3][-3]", 1},
+		{"[1, 2, 3][-4]", NULL},
+		{`"abc"[0]`, "a"},
+		{`"abc"[2]`, "c"},
+		{`"abc"[-1]`, "c"},
+		{`"abc"[3]`, NULL},
+		{`"abc"[-4]`, NULL},
+		{`""[0]`, NULL},
+		{`""[-1]`, NULL},
+		{`"日本語"[1]`, "本"},
+		{`"日本語"[-2]`, "本"},
+		{`"héllo"[1]`, "é"},
+		{`let s = "ab😀c"; s[len(s) - 2]`, "😀"},
+		{`"abc"[9223372036854775807]`, NULL},
+		{`"abc"[-9223372036854775807 - 1]`, NULL},
+		{`"abc"["a"]`, &object.Error{Message: "index operator not supported: STRING"}},
+		{`chars("日本")`, []string{"日", "本"}},
+		{`join(reverse(chars("héllo")), "")`, "olléh"},
+	}
+	runEvalTests(t, tests)
+}
+
+func TestSliceExpression(t *testing.T) {
+	tests := []evalTestCase{
+		{"[1, 2, 3, 4][1:3]", []int{2, 3}},
+		{"[1, 2, 3, 4][:2]", []int{1, 2}},
+		{"[1, 2, 3, 4][2:]", []int{3, 4}},
+		{"[1, 2, 3, 4][:]", []int{1, 2, 3, 4}},
+		{"[1, 2, 3, 4][:-1]", []int{1, 2, 3}},
+		{"[1, 2, 3, 4][-2:]", []int{3, 4}},
+		{"[1, 2, 3, 4][-10:10]", []int{1, 2, 3, 4}},
+		{"[1, 2, 3, 4][3:1]", []int{}},
+		{"[1, 2, 3, 4, 5][::2]", []int{1, 3, 5}},
+		{"[1, 2, 3, 4, 5][1::2]", []int{2, 4}},
+		{"[1, 2, 3, 4][::-1]", []int{4, 3, 2, 1}},
+		{"[1, 2, 3, 4][2::-1]", []int{3, 2, 1}},
+		{"[1, 2, 3, 4][:0:-2]", []int{4, 2}},
+		{"[1, 2, 3, 4][-1:-3:-1]", []int{4, 3}},
+		{"let a = [1, 2, 3]; let n = 1; a[n:n + 1]", []int{2}},
+		{`"hello"[1:3]`, "el"},
+		{`"hello"[:-1]`, "hell"},
+		{`"hello"[::-1]`, "olleh"},
+		{`"héllo"[1:2]`, "é"},
+		{`""[1:]`, ""},
+		{"[1, 2, 3][1::9223372036854775807]", []int{2}},
+		{"[1, 2, 3][::-9223372036854775807 - 1]", []int{3}},
+		{"[1, 2, 3][-9223372036854775807 - 1:9223372036854775807:9223372036854775807]", []int{1}},
+		{"[1, 2, 3][9223372036854775807:-9223372036854775807 - 1:-9223372036854775807]", []int{3}},
+		{`"abc"[1::9223372036854775807]`, "b"},
+		{`"abc"[::-9223372036854775807 - 1]`, "c"},
+		{`[1, 2][::0]`, &object.Error{Message: "slice step must not be zero"}},
+		{`[1, 2]["a":]`, &object.Error{Message: "slice indices must be INTEGER, got STRING"}},
+		{`{}[1:2]`, &object.Error{Message: "slice operator not supported: HASH"}},
+	}
+	runEvalTests(t, tests)
+}
+
+func TestInterpolatedStrings(t *testing.T) {
+	tests := []evalTestCase{
+		{`let name = "monkey"; "hello ${name}!"`, "hello monkey!"},
+		{`"${1 + 2} = ${3}"`, "3 = 3"},
+		{`"${[1, "a"]} ${ {"k": true} } ${#{1}}"`, "[1, a] {k: true} #{1}"},
+		{`let f = fn(x) { "<${x}>" }; "${f("${f(1)}")}"`, "<<1>>"},
+		{`"${puts()}"`, "null"},
+		{`"\${x}"`, "${x}"},
+		{`"a${"b"}c" + "d"`, "abcd"},
+		{`"a${1 + "b"}c"`, &object.Error{Message: "type mismatch: INTEGER STRING"}},
+		{`"${"${missing}"}"`, &object.Error{Message: "identifier not found: missing"}},
+		{`let f = fn() { "${1 / "x"}" }; f() + "y"`, &object.Error{Message: "type mismatch: INTEGER STRING"}},
+	}
+	runEvalTests(t, tests)
+}
+
+func testEval(input string) object.Object {
+	l := lexer.New(input)
+	p := parser.New(l)
+	program, _ := p.ParseProgram()
+	env := object.NewEnvironment()
+
+	return Eval(program, env)
+}
+
+func runEvalTests(t *testing.T, tests []evalTestCase) {
+	t.Helper()
+
+	for _, tt := range tests {
+		testExpectedObject(t, tt.input, tt.expected, testEval(tt.input))
+	}
+}
+
+func testExpectedObject(t *testing.T, input string, expected interface{}, actual object.Object) {
+	t.Helper()
+
+	switch expected := expected.(type) {
+	case int:
+		result, ok := actual.(*object.Integer)
+		if !ok || result.Value != int64(expected) {
+			t.Errorf("`%s`: wrong result. want=%d, got=%s", input, expected, inspect(actual))
+		}
+	case float64:
+		result, ok := actual.(*object.Float)
+		if !ok || result.Value != expected {
+			t.Errorf("`%s`: wrong result. want=%v, got=%s", input, expected, inspect(actual))
+		}
+	case bool:
+		if actual != nativeBoolToBooleanObject(expected) {
+			t.Errorf("`%s`: wrong result. want=%t, got=%s", input, expected, inspect(actual))
+		}
+	case string:
+		result, ok := actual.(*object.String)
+		if !ok || result.Value != expected {
+			t.Errorf("`%s`: wrong result. want=%q, got=%s", input, expected, inspect(actual))
+		}
+	case []int:
+		result, ok := actual.(*object.Array)
+		if !ok || len(result.Elements) != len(expected) {
+			t.Errorf("`%s`: wrong result. want=%v, got=%s", input, expected, inspect(actual))
+			return
+		}
+		for i, e := range expected {
+			testExpectedObject(t, input, e, result.Elements[i])
+		}
+	case []string:
+		result, ok := actual.(*object.Array)
+		if !ok || len(result.Elements) != len(expected) {
+			t.Errorf("`%s`: wrong result. want=%q, got=%s", input, expected, inspect(actual))
+			return
+		}
+		for i, e := range expected {
+			testExpectedObject(t, input, e, result.Elements[i])
+		}
+	case *object.Null:
+		if actual != NULL {
+			t.Errorf("`%s`: object is not NULL. got=%s", input, inspect(actual))
+		}
+	case *object.Error:
+		errObj, ok := actual.(*object.Error)
+		if !ok || errObj.Message != expected.Message {
+			t.Errorf("`%s`: wrong error. want=%q, got=%s", input, expected.Message, inspect(actual))
+		}
+	default:
+		t.Errorf("`%s`: unknown expected type %T", input, expected)
+	}
+}
+
+func inspect(obj object.Object) string {
+	if obj == nil {
+		return "nil"
+	}
+	return string(obj.Type()) + " " + obj.Inspect()
+}
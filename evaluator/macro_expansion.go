@@ -0,0 +1,118 @@
+package evaluator
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/object"
+	"monkey/token"
+)
+
+// maxExpansionDepth bounds the nesting of macros expanding to macro calls
+const maxExpansionDepth = 100
+
+// MacroError is a failed expansion of the macro call at Pos
+type MacroError struct {
+	Pos   token.Position
+	Macro string
+	Msg   string
+}
+
+func (e *MacroError) Error() string {
+	return fmt.Sprintf("%s: macro %s: %s", e.Pos, e.Macro, e.Msg)
+}
+
+// DefineMacros removes the top-level lets of macro literals from program and
+// binds the macros in env instead
+func DefineMacros(program *ast.Program, env *object.Environment) {
+	stmts := []ast.Statement{}
+	for _, stmt := range program.Statements {
+		if let, ok := stmt.(*ast.LetStatement); ok {
+			if ml, ok := let.Value.(*ast.MacroLiteral); ok {
+				env.Set(let.Name.Value, &object.Macro{
+					Parameters: ml.Parameters,
+					Body:       ml.Body,
+					Env:        env,
+				})
+				continue
+			}
+		}
+		stmts = append(stmts, stmt)
+	}
+	program.Statements = stmts
+}
+
+// ExpandMacros replaces the calls of the macros in env by their expansions,
+// it is run on a program before it's compiled or evaluated
+func ExpandMacros(program *ast.Program, env *object.Environment) error {
+	_, err := expandMacros(program, env, 0)
+	return err
+}
+
+func expandMacros(node ast.Node, env *object.Environment, depth int) (ast.Node, error) {
+	var err error
+
+	node = ast.Rewrite(node, func(node ast.Node) ast.Node {
+		call, ok := node.(*ast.CallExpression)
+		if !ok || err != nil {
+			return node
+		}
+		ident, ok := call.Function.(*ast.Identifier)
+		if !ok {
+			return node
+		}
+		obj, ok := env.Get(ident.Value)
+		if !ok {
+			return node
+		}
+		macro, ok := obj.(*object.Macro)
+		if !ok {
+			return node
+		}
+
+		expansion, e := expandMacroCall(call, ident, macro, env, depth)
+		if e != nil {
+			err = e
+			return node
+		}
+		return expansion
+	})
+	return node, err
+}
+
+func expandMacroCall(
+	call *ast.CallExpression,
+	ident *ast.Identifier,
+	macro *object.Macro,
+	env *object.Environment,
+	depth int,
+) (ast.Node, error) {
+	fail := func(format string, a ...interface{}) error {
+		return &MacroError{Pos: ident.Token.Pos, Macro: ident.Value, Msg: fmt.Sprintf(format, a...)}
+	}
+
+	if depth == maxExpansionDepth {
+		return nil, fail("expansion nested too deeply")
+	}
+	if len(call.Arguments) != len(macro.Parameters) {
+		return nil, fail("wrong number of arguments: want=%d, got=%d",
+			len(macro.Parameters), len(call.Arguments))
+	}
+
+	evalEnv := object.NewEnclosedEnvironment(macro.Env)
+	for i, param := range macro.Parameters {
+		evalEnv.Set(param.Value, &object.Quote{Node: call.Arguments[i]})
+	}
+
+	result := unwrapReturnValue(Eval(macro.Body, evalEnv))
+	switch result := result.(type) {
+	case *object.Error:
+		return nil, fail("%s", result.Message)
+	case *object.Quote:
+		if _, ok := result.Node.(ast.Expression); !ok {
+			return nil, fail("must return a quoted expression")
+		}
+		// the expansion may call macros in turn
+		return expandMacros(result.Node, env, depth+1)
+	}
+	return nil, fail("must return QUOTE, got %s", typeOf(result))
+}
@@ -0,0 +1,56 @@
+package evaluator
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/module"
+	"monkey/object"
+)
+
+// ModuleLoader loads the modules of import expressions, nested imports done
+// by a module go through it as well
+var ModuleLoader *module.Loader
+
+func init() {
+	ModuleLoader = NewModuleLoader(module.DefaultSearchPath()...)
+}
+
+// NewModuleLoader returns a loader which evaluates every imported module in
+// an environment of its own
+func NewModuleLoader(searchPath ...string) *module.Loader {
+	return module.NewLoader(evalModule, searchPath...)
+}
+
+func evalModule(_ *module.Loader, program *ast.Program) (*object.Hash, error) {
+	env := object.NewEnvironment()
+
+	DefineMacros(program, env)
+	if err := ExpandMacros(program, env); err != nil {
+		return nil, err
+	}
+
+	result := Eval(program, env)
+	if err, ok := result.(*object.Error); ok {
+		return nil, fmt.Errorf("%s", err.Message)
+	}
+
+	exports := object.NewHash()
+	for _, name := range env.Names() {
+		value, _ := env.Get(name)
+		exports.Set(&object.String{Value: name}, value)
+	}
+	return exports, nil
+}
+
+func evalImportExpression(path object.Object) object.Object {
+	str, ok := path.(*object.String)
+	if !ok {
+		return newError("import path must be STRING, got %s", path.Type())
+	}
+
+	mod, err := ModuleLoader.Load(str.Value)
+	if err != nil {
+		return newError("%s", err)
+	}
+	return mod
+}
@@ -0,0 +1,137 @@
+package evaluator
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/object"
+	"monkey/token"
+	"strconv"
+)
+
+// gensym numbers the fresh names given to the bindings of quotes
+var gensym int
+
+// quote returns node with its unquote calls replaced by their values. Names
+// bound inside node are renamed, so that they neither capture nor shadow the
+// names of the code the quote ends up in.
+func quote(node ast.Node, env *object.Environment) object.Object {
+	node = ast.Copy(node) // unquote rewrites it
+	renameBindings(node)
+
+	node, err := evalUnquoteCalls(node, env)
+	if err != nil {
+		return err
+	}
+	return &object.Quote{Node: node}
+}
+
+func isCallOf(call *ast.CallExpression, name string) bool {
+	ident, ok := call.Function.(*ast.Identifier)
+	return ok && ident.Value == name
+}
+
+func isUnquoteCall(node ast.Node) bool {
+	call, ok := node.(*ast.CallExpression)
+	return ok && isCallOf(call, "unquote") && len(call.Arguments) == 1
+}
+
+// renameBindings gives the names bound by lets and parameters in node fresh
+// names, which no identifier of the source can have. The arguments of
+// unquote calls are left alone.
+func renameBindings(node ast.Node) {
+	renamed := map[string]string{}
+	bind := func(name string) {
+		if _, ok := renamed[name]; !ok {
+			gensym++
+			renamed[name] = fmt.Sprintf("%s@%d", name, gensym)
+		}
+	}
+
+	idents := []*ast.Identifier{}
+	functions := []*ast.FunctionLiteral{}
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			bind(node.Name.Value)
+		case *ast.FunctionLiteral:
+			for _, param := range node.Parameters {
+				bind(param.Value)
+			}
+			functions = append(functions, node)
+		case *ast.Identifier:
+			idents = append(idents, node)
+		}
+		return !isUnquoteCall(node)
+	})
+
+	for _, ident := range idents {
+		if name, ok := renamed[ident.Value]; ok {
+			ident.Value = name
+			ident.Token.Literal = name
+		}
+	}
+	for _, fl := range functions {
+		if name, ok := renamed[fl.Name]; ok {
+			fl.Name = name
+		}
+	}
+}
+
+func evalUnquoteCalls(quoted ast.Node, env *object.Environment) (ast.Node, *object.Error) {
+	var err *object.Error
+
+	node := ast.Rewrite(quoted, func(node ast.Node) ast.Node {
+		if err != nil || !isUnquoteCall(node) {
+			return node
+		}
+
+		unquoted := Eval(node.(*ast.CallExpression).Arguments[0], env)
+		if isError(unquoted) {
+			err = unquoted.(*object.Error)
+			return node
+		}
+		exp, ok := convertObjectToASTNode(unquoted)
+		if !ok {
+			err = newError("cannot unquote %s", typeOf(unquoted))
+			return node
+		}
+		return exp
+	})
+	return node, err
+}
+
+// convertObjectToASTNode returns the literal of obj
+func convertObjectToASTNode(obj object.Object) (ast.Expression, bool) {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		t := token.Token{Type: token.INT, Literal: strconv.FormatInt(obj.Value, 10)}
+		return &ast.IntegerLiteral{Token: t, Value: obj.Value}, true
+
+	case *object.Float:
+		t := token.Token{Type: token.FLOAT, Literal: strconv.FormatFloat(obj.Value, 'g', -1, 64)}
+		return &ast.FloatLiteral{Token: t, Value: obj.Value}, true
+
+	case *object.Boolean:
+		t := token.Token{Type: token.FALSE, Literal: "false"}
+		if obj.Value {
+			t = token.Token{Type: token.TRUE, Literal: "true"}
+		}
+		return &ast.Boolean{Token: t, Value: obj.Value}, true
+
+	case *object.String:
+		t := token.Token{Type: token.STRING, Literal: obj.Value}
+		return &ast.StringLiteral{Token: t, Value: obj.Value}, true
+
+	case *object.Quote:
+		exp, ok := obj.Node.(ast.Expression)
+		return exp, ok
+	}
+	return nil, false
+}
+
+func typeOf(obj object.Object) object.ObjectType {
+	if obj == nil {
+		return object.NULL_OBJ
+	}
+	return obj.Type()
+}
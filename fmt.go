@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"monkey/format"
+	"monkey/parser"
+	"os"
+)
+
+// fmtMain runs `monkey fmt [-w] [-d] [files]`, which formats the files, or
+// stdin without files, and returns the exit code
+func fmtMain(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	write := flags.Bool("w", false, "write the result to the file instead of stdout")
+	showDiff := flags.Bool("d", false, "print a diff instead of the formatted source")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: monkey fmt [-w] [-d] [files]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "fmt: cannot use -w with stdin")
+			return 2
+		}
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		if !formatFile("<stdin>", src, false, *showDiff) {
+			return 2
+		}
+		return 0
+	}
+
+	code := 0
+	for _, path := range flags.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 2
+			continue
+		}
+		if !formatFile(path, src, *write, *showDiff) {
+			code = 2
+		}
+	}
+	return code
+}
+
+func formatFile(path string, src []byte, write, showDiff bool) bool {
+	res, err := format.Source(src)
+	if err != nil {
+		if errors, ok := err.(parser.ErrorList); ok {
+			for _, e := range errors {
+				fmt.Fprintf(os.Stderr, "%s:%s\n", path, e)
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+		}
+		return false
+	}
+
+	if showDiff && !bytes.Equal(src, res) {
+		fmt.Print(diff(path, src, res))
+	}
+	if write && !bytes.Equal(src, res) {
+		if err := os.WriteFile(path, res, 0644); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return false
+		}
+	}
+	if !write && !showDiff {
+		os.Stdout.Write(res)
+	}
+	return true
+}
@@ -0,0 +1,392 @@
+// Package format implements the canonical formatting of Monkey source code
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"monkey/token"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	indentWidth = 4
+	lineWidth   = 80 // lists longer than this are broken into lines
+)
+
+// Source formats the program in src, it fails if src has syntax errors.
+// Comments are kept, though comments inside an expression are moved behind
+// the statement holding it.
+func Source(src []byte) ([]byte, error) {
+	p := parser.New(lexer.New(string(src)))
+	program, err := p.ParseProgram()
+	if err != nil {
+		return nil, err
+	}
+
+	pr := &printer{
+		lines:    strings.Split(string(src), "\n"),
+		comments: program.Comments,
+	}
+	var out bytes.Buffer
+	pr.statements(&out, program.Statements, token.Position{Line: len(pr.lines) + 1}, 0, false)
+	return out.Bytes(), nil
+}
+
+type printer struct {
+	lines    []string // of the source
+	comments []token.Comment
+	next     int // the next comment to print
+}
+
+// statements writes stmts one per line, with the comments before end that
+// are still to print. In a block the last expression statement is its value
+// and has no semicolon.
+func (p *printer) statements(out *bytes.Buffer, stmts []ast.Statement, end token.Position, indent int, block bool) {
+	first := true
+	for i, s := range stmts {
+		pos := statementPos(s)
+		first = p.flushComments(out, pos, indent, first)
+
+		if !first && p.blankBefore(pos.Line) {
+			out.WriteString("\n")
+		}
+		out.WriteString(indentation(indent))
+		out.WriteString(p.statement(s, indent, block && i == len(stmts)-1))
+		out.WriteString("\n")
+		first = false
+	}
+	p.flushComments(out, end, indent, first)
+}
+
+// flushComments writes the comments before pos. A comment on the line of
+// what precedes it stays there, others get a line of their own.
+func (p *printer) flushComments(out *bytes.Buffer, pos token.Position, indent int, first bool) bool {
+	for ; p.next < len(p.comments) && before(p.comments[p.next].Pos, pos); p.next++ {
+		c := p.comments[p.next]
+		if !c.NewLine && out.Len() > 0 {
+			out.Truncate(out.Len() - 1) // the trailing newline
+			out.WriteString(" " + c.Text + "\n")
+			continue
+		}
+		if !first && p.blankBefore(c.Pos.Line) {
+			out.WriteString("\n")
+		}
+		out.WriteString(indentation(indent) + c.Text + "\n")
+		first = false
+	}
+	return first
+}
+
+func (p *printer) statement(s ast.Statement, indent int, value bool) string {
+	col := indent * indentWidth
+
+	switch s := s.(type) {
+	case *ast.LetStatement:
+		prefix := s.Token.Literal + " " + s.Name.Value
+		if s.Type != nil {
+			prefix += ": " + s.Type.String()
+		}
+		prefix += " = "
+		return prefix + p.expr(s.Value, indent, col+len(prefix)) + ";"
+
+	case *ast.ReturnStatement:
+		return "return " + p.expr(s.ReturnValue, indent, col+len("return ")) + ";"
+
+	case *ast.ExpressionStatement:
+		exp := p.expr(s.Expression, indent, col)
+		if _, ok := s.Expression.(*ast.IfExpression); ok || value {
+			return exp
+		}
+		return exp + ";"
+	}
+	panic(fmt.Sprintf("format: unexpected statement %T", s))
+}
+
+// precedence of expressions as operands, like the parser's
+func precedence(e ast.Expression) int {
+	switch e := e.(type) {
+	case *ast.InfixExpression:
+		switch e.Operator {
+		case "==", "!=":
+			return parser.EQUALS
+		case "<", ">", "in":
+			return parser.LESSGREATER
+		case "+", "-":
+			return parser.SUM
+		default:
+			return parser.PRODUCT
+		}
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+	case *ast.CallExpression, *ast.IndexExpression, *ast.SliceExpression:
+		return parser.CALL
+	}
+	return parser.INDEX + 1
+}
+
+// operand formats e, in parentheses if it binds less than prec
+func (p *printer) operand(e ast.Expression, prec int, indent, col int) string {
+	if precedence(e) < prec {
+		return "(" + p.expr(e, indent, col+1) + ")"
+	}
+	return p.expr(e, indent, col)
+}
+
+// expr formats e starting at column col of a line indented by indent
+func (p *printer) expr(e ast.Expression, indent, col int) string {
+	switch e := e.(type) {
+	case *ast.Identifier:
+		return e.Value
+
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.Boolean:
+		return e.TokenLiteral()
+
+	case *ast.StringLiteral:
+		if p.isRaw(e.Token.Pos) {
+			return "`" + e.Value + "`"
+		}
+		return `"` + escape(e.Value) + `"`
+
+	case *ast.InterpolatedString:
+		var b strings.Builder
+		b.WriteString(`"`)
+		for i, exp := range e.Expressions {
+			b.WriteString(escape(e.Strings[i]) + "${")
+			b.WriteString(p.expr(exp, indent, column(col, b.String())))
+			b.WriteString("}")
+		}
+		b.WriteString(escape(e.Strings[len(e.Strings)-1]) + `"`)
+		return b.String()
+
+	case *ast.PrefixExpression:
+		return e.Operator + p.operand(e.Right, parser.PREFIX, indent, col+len(e.Operator))
+
+	case *ast.InfixExpression:
+		prec := precedence(e)
+		left := p.operand(e.Left, prec, indent, col)
+		left += " " + e.Operator + " "
+		// operators are left associative, so the right operand needs
+		// parentheses also at the same precedence
+		return left + p.operand(e.Right, prec+1, indent, column(col, left))
+
+	case *ast.IfExpression:
+		s := "if (" + p.expr(e.Condition, indent, col+len("if (")) + ") "
+		s += p.block(e.Consequence, indent, column(col, s))
+		if e.Alternative != nil {
+			s += " else " + p.block(e.Alternative, indent, column(col, s)+len(" else "))
+		}
+		return s
+
+	case *ast.FunctionLiteral:
+		s := "fn" + p.list("(", ")", len(e.Parameters), func(i, indent, col int) string {
+			if i < len(e.ParameterTypes) && e.ParameterTypes[i] != nil {
+				return e.Parameters[i].Value + ": " + e.ParameterTypes[i].String()
+			}
+			return e.Parameters[i].Value
+		}, indent, col+len("fn"))
+		if e.ReturnType != nil {
+			s += " -> " + e.ReturnType.String()
+		}
+		return s + " " + p.block(e.Body, indent, column(col, s)+1)
+
+	case *ast.MacroLiteral:
+		s := "macro" + p.list("(", ")", len(e.Parameters), func(i, indent, col int) string {
+			return e.Parameters[i].Value
+		}, indent, col+len("macro"))
+		return s + " " + p.block(e.Body, indent, column(col, s)+1)
+
+	case *ast.CallExpression:
+		s := p.operand(e.Function, parser.CALL, indent, col)
+		return s + p.list("(", ")", len(e.Arguments), func(i, indent, col int) string {
+			return p.expr(e.Arguments[i], indent, col)
+		}, indent, column(col, s))
+
+	case *ast.IndexExpression:
+		s := p.operand(e.Left, parser.CALL, indent, col) + "["
+		return s + p.expr(e.Index, indent, column(col, s)) + "]"
+
+	case *ast.SliceExpression:
+		s := p.operand(e.Left, parser.CALL, indent, col) + "["
+		if e.Start != nil {
+			s += p.expr(e.Start, indent, column(col, s))
+		}
+		s += ":"
+		if e.End != nil {
+			s += p.expr(e.End, indent, column(col, s))
+		}
+		if e.Step != nil {
+			s += ":"
+			s += p.expr(e.Step, indent, column(col, s))
+		}
+		return s + "]"
+
+	case *ast.ArrayLiteral:
+		return p.list("[", "]", len(e.Elements), func(i, indent, col int) string {
+			return p.expr(e.Elements[i], indent, col)
+		}, indent, col)
+
+	case *ast.SetLiteral:
+		return p.list("#{", "}", len(e.Elements), func(i, indent, col int) string {
+			return p.expr(e.Elements[i], indent, col)
+		}, indent, col)
+
+	case *ast.HashLiteral:
+		return p.list("{", "}", len(e.Keys), func(i, indent, col int) string {
+			key := p.expr(e.Keys[i], indent, col) + ": "
+			return key + p.expr(e.Pairs[e.Keys[i]], indent, column(col, key))
+		}, indent, col)
+
+	case *ast.ImportExpression:
+		return "import " + p.expr(e.Path, indent, col+len("import "))
+	}
+	panic(fmt.Sprintf("format: unexpected expression %T", e))
+}
+
+// list formats n items between open and close on one line, or one per line
+// if that gets too long
+func (p *printer) list(open, close string, n int, item func(i, indent, col int) string, indent, col int) string {
+	mark := p.next
+
+	var b strings.Builder
+	b.WriteString(open)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(item(i, indent, column(col, b.String())))
+	}
+	b.WriteString(close)
+
+	flat := b.String()
+	if n < 2 || column(col, firstLine(flat)) <= lineWidth {
+		return flat
+	}
+
+	p.next = mark // the items are formatted again
+	b.Reset()
+	b.WriteString(open + "\n")
+	for i := 0; i < n; i++ {
+		b.WriteString(indentation(indent + 1))
+		b.WriteString(item(i, indent+1, (indent+1)*indentWidth))
+		if i < n-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(indentation(indent) + close)
+	return b.String()
+}
+
+// block formats a block starting at column col. A block of one statement
+// written on one line stays so if it fits.
+func (p *printer) block(block *ast.BlockStatement, indent, col int) string {
+	comments := p.commentsIn(block.Token.Pos, block.Rbrace)
+	if len(block.Statements) == 0 && !comments {
+		return "{}"
+	}
+
+	if len(block.Statements) == 1 && !comments && block.Token.Pos.Line == block.Rbrace.Line {
+		mark := p.next
+		s := "{ " + p.statement(block.Statements[0], indent, true) + " }"
+		if !strings.Contains(s, "\n") && column(col, s) <= lineWidth {
+			return s
+		}
+		p.next = mark
+	}
+
+	var out bytes.Buffer
+	out.WriteString("{\n")
+	p.statements(&out, block.Statements, block.Rbrace, indent+1, true)
+	out.WriteString(indentation(indent) + "}")
+	return out.String()
+}
+
+// commentsIn reports whether comments to print are between from and to
+func (p *printer) commentsIn(from, to token.Position) bool {
+	for _, c := range p.comments[p.next:] {
+		if !before(c.Pos, to) {
+			break
+		}
+		if before(from, c.Pos) {
+			return true
+		}
+	}
+	return false
+}
+
+// blankBefore reports whether the source line before line is blank
+func (p *printer) blankBefore(line int) bool {
+	return line >= 2 && line-2 < len(p.lines) && strings.TrimSpace(p.lines[line-2]) == ""
+}
+
+// isRaw reports whether the string at pos is a raw string
+func (p *printer) isRaw(pos token.Position) bool {
+	if pos.Line < 1 || pos.Line > len(p.lines) {
+		return false
+	}
+	line := []rune(p.lines[pos.Line-1])
+	return pos.Column >= 1 && pos.Column <= len(line) && line[pos.Column-1] == '`'
+}
+
+func statementPos(s ast.Statement) token.Position {
+	switch s := s.(type) {
+	case *ast.LetStatement:
+		return s.Token.Pos
+	case *ast.ReturnStatement:
+		return s.Token.Pos
+	case *ast.ExpressionStatement:
+		return s.Token.Pos
+	}
+	return token.Position{}
+}
+
+func before(a, b token.Position) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+}
+
+// escape s for a double quoted string
+func escape(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteString(`\` + string(r))
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '$' && strings.HasPrefix(s[i+1:], "{"):
+			b.WriteString(`\$`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\u{%x}`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func indentation(indent int) string {
+	return strings.Repeat(" ", indent*indentWidth)
+}
+
+// column returns the column after writing s at column col
+func column(col int, s string) int {
+	if i := strings.LastIndex(s, "\n"); i >= 0 {
+		return utf8.RuneCountInString(s[i+1:])
+	}
+	return col + utf8.RuneCountInString(s)
+}
+
+func firstLine(s string) string {
+	if i := strings.Index(s, "\n"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
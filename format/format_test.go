@@ -0,0 +1,115 @@
+package format
+
+import "testing"
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a=1\nlet  b = a+2*3", "let a = 1;\nlet b = a + 2 * 3;\n"},
+		{"(1 + 2) * 3 - (4 - 5)", "(1 + 2) * 3 - (4 - 5);\n"},
+		{"((a - b) - c) + !(x == y)", "a - b - c + !(x == y);\n"},
+		{"-(-1); (-f)(1); (a + b)[0]", "--1;\n(-f)(1);\n(a + b)[0];\n"},
+		{"a[1:] + a[::2] + a[:2:]", "a[1:] + a[::2] + a[:2];\n"},
+		{`"a\"b\n${x + 1}\${y}"`, "\"a\\\"b\\n${x + 1}\\${y}\";\n"},
+		{"`raw\n  ${x}`", "`raw\n  ${x}`;\n"},
+		{"{\"a\":1,2:[]}; #{1,2}; import \"m\"", "{\"a\": 1, 2: []};\n#{1, 2};\nimport \"m\";\n"},
+		{"0x1F + 1_000 + 1.5e3", "0x1F + 1_000 + 1.5e3;\n"},
+		{"let x:int=1; let f = fn(a:string,b : [int])->bool { a }", "let x: int = 1;\nlet f = fn(a: string, b: [int]) -> bool { a };\n"},
+		{"let m = macro(a,b) { quote(unquote(a) + unquote(b)) }", "let m = macro(a, b) { quote(unquote(a) + unquote(b)) };\n"},
+		{
+			"let f = fn(a,b){ return a }; fn() {}",
+			"let f = fn(a, b) { return a; };\nfn() {};\n",
+		},
+		{
+			"let f = fn(x) {\nlet y = x\ny }",
+			"let f = fn(x) {\n    let y = x;\n    y\n};\n",
+		},
+		{
+			"if (a) { 1 } else {\n2 }\nif (b) {\nif (c) { 3 }\n}",
+			"if (a) { 1 } else {\n    2\n}\nif (b) {\n    if (c) { 3 }\n}\n",
+		},
+		{
+			"let long = [1111111111, 2222222222, 3333333333, 4444444444, 5555555555, 6666666666];",
+			"let long = [\n    1111111111,\n    2222222222,\n    3333333333,\n    4444444444,\n    5555555555,\n    6666666666\n];\n",
+		},
+		{
+			"f(fn(x) { x }, {\"aaaaaaaaaaaa\": 1, \"bbbbbbbbbbbbb\": 2, \"cccccccccccc\": 3, \"dddddddddddd\": 4})",
+			"f(fn(x) { x }, {\n    \"aaaaaaaaaaaa\": 1,\n    \"bbbbbbbbbbbbb\": 2,\n    \"cccccccccccc\": 3,\n    \"dddddddddddd\": 4\n});\n",
+		},
+		{
+			"let a = 1;\n\n\n\nlet b = 2;\nlet c = 3;\n",
+			"let a = 1;\n\nlet b = 2;\nlet c = 3;\n",
+		},
+	}
+
+	for _, tt := range tests {
+		testFormat(t, tt.input, tt.expected)
+	}
+}
+
+func TestComments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"# setup\nlet x = 5; // five\n\n\n// y\nlet y = 6; /* six */\n// end",
+			"# setup\nlet x = 5; // five\n\n// y\nlet y = 6; /* six */\n// end\n",
+		},
+		{
+			"let f = fn(a) { // body\n  let b = a;\n\n  // last\n  b\n  // after\n}",
+			"let f = fn(a) { // body\n    let b = a;\n\n    // last\n    b\n    // after\n};\n",
+		},
+		{
+			"fn() {\n// only\n}",
+			"fn() {\n    // only\n};\n",
+		},
+		{
+			"let y = fn(a /* unused */) { a };",
+			"let y = fn(a) { a }; /* unused */\n",
+		},
+		{
+			"let a = [1, // one\n  2];\nlet b = 1;",
+			"let a = [1, 2]; // one\nlet b = 1;\n",
+		},
+	}
+
+	for _, tt := range tests {
+		testFormat(t, tt.input, tt.expected)
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	_, err := Source([]byte("let = 1"))
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if err.Error() != "1:5: expected next token to be IDENT, got = instead" {
+		t.Errorf("wrong error. got=%q", err)
+	}
+}
+
+func testFormat(t *testing.T, input, expected string) {
+	t.Helper()
+
+	out, err := Source([]byte(input))
+	if err != nil {
+		t.Errorf("%q: %s", input, err)
+		return
+	}
+	if string(out) != expected {
+		t.Errorf("%q: wrong result.\nwant=%q\ngot= %q", input, expected, out)
+		return
+	}
+
+	again, err := Source(out)
+	if err != nil {
+		t.Errorf("%q: formatted source has errors: %s", input, err)
+		return
+	}
+	if string(again) != string(out) {
+		t.Errorf("%q: not idempotent.\nfirst= %q\nagain= %q", input, out, again)
+	}
+}
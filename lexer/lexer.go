@@ -1,34 +1,93 @@
 package lexer
 
-import "monkey/token"
+import (
+	"fmt"
+	"monkey/token"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
 
 type Lexer struct {
 	input        string
 	position     int  // current position in input (points to current char)
 	readPosition int  // current reading position in input(after current char)
-	ch           byte // current char under examination
+	ch           rune // current char under examination
+	line, column int  // of current char
+
+	tokenPos  token.Position // where the current token starts
+	errors    []*Error
+	templates []template // open string interpolations, innermost last
+}
+
+type template struct {
+	depth int            // of braces within the interpolation
+	start token.Position // of the string
+}
+
+// Error is a problem in the input, like an unknown character
+type Error struct {
+	Pos token.Position
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar()
 	return l
 }
 
+// Errors returns the problems found in the input so far
+func (l *Lexer) Errors() []*Error {
+	return l.errors
+}
+
+func (l *Lexer) error(format string, a ...interface{}) {
+	l.errorAt(l.pos(), format, a...)
+}
+
+func (l *Lexer) errorAt(pos token.Position, format string, a ...interface{}) {
+	l.errors = append(l.errors, &Error{Pos: pos, Msg: fmt.Sprintf(format, a...)})
+}
+
+func (l *Lexer) pos() token.Position {
+	return token.Position{Line: l.line, Column: l.column}
+}
+
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+	l.column++
+
+	width := 1
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
-		l.ch = l.input[l.readPosition]
+		l.ch, width = utf8.DecodeRuneInString(l.input[l.readPosition:])
 	}
 	l.position = l.readPosition
-	l.readPosition += 1
+	l.readPosition += width
 }
 
+// NextToken returns the next token together with the comments before it
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
+	comments := l.readTrivia()
+	l.tokenPos = l.pos()
+	tok := l.nextToken()
+	tok.Pos = l.tokenPos
+	tok.Comments = comments
+	return tok
+}
 
-	l.skipWhitespaces()
+func (l *Lexer) nextToken() token.Token {
+	var tok token.Token
 
 	switch l.ch {
 	case '=':
@@ -42,7 +101,13 @@ func (l *Lexer) NextToken() token.Token {
 	case '+':
 		tok = newToken(token.PLUS, l.ch)
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		if l.peekChar() == '>' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{Type: token.ARROW, Literal: string(ch) + string(l.ch)}
+		} else {
+			tok = newToken(token.MINUS, l.ch)
+		}
 	case '!':
 		if l.peekChar() == '=' {
 			ch := l.ch
@@ -70,17 +135,49 @@ func (l *Lexer) NextToken() token.Token {
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
 	case '{':
+		l.openBrace()
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
-		tok = newToken(token.RBRACE, l.ch)
+		if n := len(l.templates); n > 0 && l.templates[n-1].depth == 0 {
+			// end of interpolation, the string goes on
+			literal, interpolation := l.readString(l.templates[n-1].start)
+			if interpolation {
+				tok = token.Token{Type: token.TEMPLATE_MIDDLE, Literal: literal}
+			} else {
+				l.templates = l.templates[:n-1]
+				tok = token.Token{Type: token.TEMPLATE_END, Literal: literal}
+			}
+		} else {
+			if n > 0 {
+				l.templates[n-1].depth--
+			}
+			tok = newToken(token.RBRACE, l.ch)
+		}
 	case '[':
 		tok = newToken(token.LBRACKET, l.ch)
 	case ']':
 		tok = newToken(token.RBRACKET, l.ch)
+	case '#': // followed by '{', else it starts a comment
+		ch := l.ch
+		l.readChar()
+		l.openBrace()
+		tok = token.Token{Type: token.SET_LBRACE, Literal: string(ch) + string(l.ch)}
 	case '"':
+		literal, interpolation := l.readString(l.tokenPos)
+		if interpolation {
+			l.templates = append(l.templates, template{start: l.tokenPos})
+			tok = token.Token{Type: token.TEMPLATE_START, Literal: literal}
+		} else {
+			tok = token.Token{Type: token.STRING, Literal: literal}
+		}
+	case '`':
 		tok.Type = token.STRING
-		tok.Literal = l.readString()
+		tok.Literal = l.readRawString()
 	case 0:
+		for _, t := range l.templates {
+			l.errorAt(t.start, "unterminated string interpolation")
+		}
+		l.templates = nil
 		tok.Literal = ""
 		tok.Type = token.EOF
 	default:
@@ -89,10 +186,9 @@ func (l *Lexer) NextToken() token.Token {
 			tok.Type = token.LookupIdent(tok.Literal)
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Type = token.INT
-			tok.Literal = l.readNumber()
-			return tok
+			return l.readNumber()
 		} else {
+			l.error("illegal character %q", l.ch)
 			tok = newToken(token.ILLEGAL, l.ch)
 		}
 	}
@@ -101,7 +197,7 @@ func (l *Lexer) NextToken() token.Token {
 	return tok
 }
 
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
 
@@ -114,43 +210,287 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[position:l.position]
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+func isLetter(ch rune) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' ||
+		ch >= utf8.RuneSelf && unicode.IsLetter(ch)
 }
 
-func (l *Lexer) skipWhitespaces() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+// readTrivia skips whitespace and returns the comments in between. Line
+// comments start with `//` or `#`, except for `#{` which starts a set.
+// Block comments `/* */` may be nested.
+func (l *Lexer) readTrivia() []token.Comment {
+	var comments []token.Comment
+	newLine := l.position == 0
+
+	for {
+		switch {
+		case l.ch == '\n':
+			newLine = true
+			l.readChar()
+		case l.ch == ' ' || l.ch == '\t' || l.ch == '\r':
+			l.readChar()
+		case l.ch == '/' && l.peekChar() == '/', l.ch == '#' && l.peekChar() != '{':
+			pos := l.pos()
+			comments = append(comments, token.Comment{Text: l.readLineComment(), Pos: pos, NewLine: newLine})
+			newLine = false
+		case l.ch == '/' && l.peekChar() == '*':
+			pos := l.pos()
+			comments = append(comments, token.Comment{Text: l.readBlockComment(), Pos: pos, NewLine: newLine})
+			newLine = false
+		default:
+			return comments
+		}
+	}
+}
+
+func (l *Lexer) readLineComment() string {
+	position := l.position
+	for l.ch != '\n' && !(l.ch == 0 && l.atEOF()) {
 		l.readChar()
 	}
+	return strings.TrimRight(l.input[position:l.position], "\r")
 }
 
-func (l *Lexer) readNumber() string {
+func (l *Lexer) readBlockComment() string {
+	position, pos := l.position, l.pos()
+	depth := 0
+	for {
+		switch {
+		case l.ch == '/' && l.peekChar() == '*':
+			depth++
+			l.readChar()
+		case l.ch == '*' && l.peekChar() == '/':
+			depth--
+			l.readChar()
+		case l.ch == 0 && l.atEOF():
+			l.errorAt(pos, "unterminated block comment")
+			return l.input[position:]
+		}
+		l.readChar()
+		if depth == 0 {
+			return l.input[position:l.position]
+		}
+	}
+}
+
+// readNumber reads an integer in decimal, or in hex, octal or binary with
+// prefix 0x, 0o or 0b, or a decimal float with an optional exponent. Digits
+// may be separated by single underscores. Malformed numbers are ILLEGAL.
+func (l *Lexer) readNumber() token.Token {
 	position := l.position
-	for isDigit(l.ch) {
+	var tokenType token.TokenType = token.INT
+	base := 10
+
+	if l.ch == '0' {
+		switch l.peekChar() {
+		case 'x', 'X':
+			base = 16
+		case 'o', 'O':
+			base = 8
+		case 'b', 'B':
+			base = 2
+		}
+	}
+	if base != 10 {
+		l.readChar()
+		l.readChar()
+	} else {
+		l.skipDigits()
+		if l.ch == '.' && isDigit(l.peekChar()) {
+			tokenType = token.FLOAT
+			l.readChar()
+			l.skipDigits()
+		}
+		if l.ch == 'e' || l.ch == 'E' {
+			tokenType = token.FLOAT
+			l.readChar()
+			if l.ch == '+' || l.ch == '-' {
+				l.readChar()
+			}
+		}
+	}
+	// anything alphanumeric is taken as part of the number, so that e.g.
+	// 0b12 or 12ab get reported as a whole
+	for isDigit(l.ch) || 'a' <= l.ch && l.ch <= 'z' || 'A' <= l.ch && l.ch <= 'Z' || l.ch == '_' {
 		l.readChar()
 	}
-	return l.input[position:l.position]
+
+	literal := l.input[position:l.position]
+	if problem := checkNumber(literal, base, tokenType == token.FLOAT); problem != "" {
+		l.errorAt(l.tokenPos, "invalid number %q: %s", literal, problem)
+		return token.Token{Type: token.ILLEGAL, Literal: literal}
+	}
+	return token.Token{Type: tokenType, Literal: literal}
+}
+
+func (l *Lexer) skipDigits() {
+	for isDigit(l.ch) || l.ch == '_' {
+		l.readChar()
+	}
+}
+
+var baseNames = map[int]string{2: "binary", 8: "octal", 10: "decimal", 16: "hex"}
+
+// checkNumber returns what is wrong with a number literal, if anything
+func checkNumber(literal string, base int, isFloat bool) string {
+	digits := literal
+	if base != 10 {
+		digits = literal[2:]
+		if strings.Trim(digits, "_") == "" {
+			return "missing digits after " + literal[:2]
+		}
+	}
+
+	if base == 10 && !isFloat && len(digits) > 1 && digits[0] == '0' {
+		return "leading zero in decimal literal, use 0o for octal"
+	}
+
+	parts := []string{digits}
+	if isFloat {
+		mantissa, exponent := digits, ""
+		if i := strings.IndexAny(digits, "eE"); i >= 0 {
+			mantissa, exponent = digits[:i], strings.TrimLeft(digits[i+1:], "+-")
+			if exponent == "" {
+				return "exponent has no digits"
+			}
+		}
+		parts = append(strings.Split(mantissa, "."), exponent)
+	}
+
+	for i, part := range parts {
+		// an underscore may follow the prefix, else it goes between digits
+		trimmed := part
+		if i == 0 && base != 10 {
+			trimmed = strings.TrimPrefix(part, "_")
+		}
+		if strings.HasPrefix(trimmed, "_") || strings.HasSuffix(part, "_") ||
+			strings.Contains(part, "__") {
+			return "'_' must separate successive digits"
+		}
+
+		for _, ch := range part {
+			if ch != '_' && digitValue(ch) >= base {
+				return fmt.Sprintf("invalid digit %q in %s literal", ch, baseNames[base])
+			}
+		}
+	}
+	return ""
 }
 
-func isDigit(ch byte) bool {
+func digitValue(ch rune) int {
+	switch {
+	case '0' <= ch && ch <= '9':
+		return int(ch - '0')
+	case 'a' <= ch && ch <= 'z':
+		return int(ch-'a') + 10
+	case 'A' <= ch && ch <= 'Z':
+		return int(ch-'A') + 10
+	default:
+		return 36
+	}
+}
+
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
-func (l *Lexer) readString() string {
+func (l *Lexer) openBrace() {
+	if n := len(l.templates); n > 0 {
+		l.templates[n-1].depth++
+	}
+}
+
+func (l *Lexer) atEOF() bool {
+	return l.position >= len(l.input)
+}
+
+// readString reads a string in double quotes, which starts at start, and
+// replaces its escape sequences. It stops early at the start of an
+// interpolation `${`.
+func (l *Lexer) readString(start token.Position) (string, bool) {
+	var out strings.Builder
+	for {
+		l.readChar()
+		switch {
+		case l.ch == '"':
+			return out.String(), false
+		case l.ch == '$' && l.peekChar() == '{':
+			l.readChar()
+			return out.String(), true
+		case l.ch == 0 && l.atEOF():
+			l.errorAt(start, "unterminated string")
+			return out.String(), false
+		case l.ch == '\\':
+			pos := l.pos()
+			l.readChar()
+			l.readEscape(&out, pos)
+		default:
+			out.WriteRune(l.ch)
+		}
+	}
+}
+
+// readEscape writes the char of the escape sequence whose backslash at pos
+// was just read
+func (l *Lexer) readEscape(out *strings.Builder, pos token.Position) {
+	switch l.ch {
+	case 'n':
+		out.WriteRune('\n')
+	case 't':
+		out.WriteRune('\t')
+	case 'r':
+		out.WriteRune('\r')
+	case '\\', '"', '$':
+		out.WriteRune(l.ch)
+	case 'u':
+		if l.peekChar() != '{' {
+			l.errorAt(pos, "invalid unicode escape, want \\u{...}")
+			return
+		}
+		l.readChar()
+		position := l.readPosition
+		for l.peekChar() != '}' && l.peekChar() != '"' && l.peekChar() != 0 {
+			l.readChar()
+		}
+		digits := l.input[position:l.readPosition]
+		if l.peekChar() == '}' {
+			l.readChar()
+		}
+
+		code, err := strconv.ParseUint(digits, 16, 32)
+		if err != nil || len(digits) > 6 || !utf8.ValidRune(rune(code)) {
+			l.errorAt(pos, "invalid unicode escape \\u{%s}", digits)
+			return
+		}
+		out.WriteRune(rune(code))
+	default:
+		if !l.atEOF() { // else readString reports the missing quote
+			l.errorAt(pos, "unknown escape sequence \\%c", l.ch)
+		}
+	}
+}
+
+// readRawString reads a string in backticks, which may span lines and has no
+// escape sequences
+func (l *Lexer) readRawString() string {
 	position := l.position + 1
 	for {
 		l.readChar()
-		if l.ch == '"' || l.ch == 0 {
+		if l.ch == '`' {
+			break
+		}
+		if l.ch == 0 && l.atEOF() {
+			l.errorAt(l.tokenPos, "unterminated raw string")
 			break
 		}
 	}
 	return l.input[position:l.position]
 }
 
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
 	if l.readPosition >= len(l.input) {
 		return 0
-	} else {
-		return l.input[l.readPosition]
 	}
+	ch, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	return ch
 }
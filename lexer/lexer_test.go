@@ -38,3 +38,340 @@ func TextNextToken(t *testing.T) {
         }
     }
 }
+
+func TestUnicode(t *testing.T) {
+    input := `let größe = "日本語"; _名前1 ü`
+
+    tests := []struct {
+        expectedType token.TokenType
+        expectedLiteral string
+    }{
+        {token.LET, "let"},
+        {token.IDENT, "größe"},
+        {token.ASSIGN, "="},
+        {token.STRING, "日本語"},
+        {token.SEMICOLON, ";"},
+        {token.IDENT, "_名前1"},
+        {token.IDENT, "ü"},
+        {token.EOF, ""},
+    }
+
+    l := New(input)
+    for i, tt := range tests {
+        tok := l.NextToken()
+
+        if tok.Type != tt.expectedType {
+            t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+                i, tt.expectedType, tok.Type)
+        }
+
+        if tok.Literal != tt.expectedLiteral {
+            t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+                i, tt.expectedLiteral, tok.Literal)
+        }
+    }
+
+    l = New("a → b")
+    l.NextToken()
+    if tok := l.NextToken(); tok.Type != token.ILLEGAL || tok.Literal != "→" {
+        t.Fatalf("non-letter not ILLEGAL. got=%q (%q)", tok.Type, tok.Literal)
+    }
+}
+
+func TestStrings(t *testing.T) {
+    tests := []struct {
+        input string
+        expectedLiteral string
+        expectedErrors []string
+    }{
+        {`"a\nb\tc\r"`, "a\nb\tc\r", nil},
+        {`"say \"hi\" \\o/"`, `say "hi" \o/`, nil},
+        {`"\u{41}\u{e9}\u{1F600}"`, "Aé😀", nil},
+        {"`raw \\n\n\"line\"`", "raw \\n\n\"line\"", nil},
+        {`"abc`, "abc", []string{"1:1: unterminated string"}},
+        {`"abc\`, "abc", []string{"1:1: unterminated string"}},
+        {"`abc", "abc", []string{"1:1: unterminated raw string"}},
+        {`"a\qb"`, "ab", []string{`1:3: unknown escape sequence \q`}},
+        {`"\u41"`, "41", []string{`1:2: invalid unicode escape, want \u{...}`}},
+        {`"\u{110000}"`, "", []string{`1:2: invalid unicode escape \u{110000}`}},
+        {`"\u{zz}"`, "", []string{`1:2: invalid unicode escape \u{zz}`}},
+    }
+
+    for _, tt := range tests {
+        l := New(tt.input)
+        tok := l.NextToken()
+
+        if tok.Type != token.STRING {
+            t.Fatalf("%s: tokentype wrong. expected=%q, got=%q", tt.input, token.STRING, tok.Type)
+        }
+        if tok.Literal != tt.expectedLiteral {
+            t.Errorf("%s: literal wrong. expected=%q, got=%q", tt.input, tt.expectedLiteral, tok.Literal)
+        }
+        if tok := l.NextToken(); tok.Type != token.EOF {
+            t.Errorf("%s: expected EOF after string. got=%q", tt.input, tok.Type)
+        }
+        if len(l.Errors()) != len(tt.expectedErrors) {
+            t.Fatalf("%s: wrong errors. expected=%q, got=%q", tt.input, tt.expectedErrors, l.Errors())
+        }
+        for i, err := range l.Errors() {
+            if err.Error() != tt.expectedErrors[i] {
+                t.Errorf("%s: wrong error. expected=%q, got=%q", tt.input, tt.expectedErrors[i], err)
+            }
+        }
+    }
+}
+
+func TestInterpolation(t *testing.T) {
+    input := `"a${x}b${ {1: "c${y}"}[1] }\${z}"`
+
+    tests := []struct {
+        expectedType token.TokenType
+        expectedLiteral string
+    }{
+        {token.TEMPLATE_START, "a"},
+        {token.IDENT, "x"},
+        {token.TEMPLATE_MIDDLE, "b"},
+        {token.LBRACE, "{"},
+        {token.INT, "1"},
+        {token.COLON, ":"},
+        {token.TEMPLATE_START, "c"},
+        {token.IDENT, "y"},
+        {token.TEMPLATE_END, ""},
+        {token.RBRACE, "}"},
+        {token.LBRACKET, "["},
+        {token.INT, "1"},
+        {token.RBRACKET, "]"},
+        {token.TEMPLATE_END, "${z}"},
+        {token.EOF, ""},
+    }
+
+    l := New(input)
+    for i, tt := range tests {
+        tok := l.NextToken()
+
+        if tok.Type != tt.expectedType {
+            t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+                i, tt.expectedType, tok.Type)
+        }
+
+        if tok.Literal != tt.expectedLiteral {
+            t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+                i, tt.expectedLiteral, tok.Literal)
+        }
+    }
+    if len(l.Errors()) != 0 {
+        t.Fatalf("unexpected errors: %q", l.Errors())
+    }
+
+    l = New(`"a${x`)
+    for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+    }
+    if len(l.Errors()) != 1 || l.Errors()[0].Error() != "1:1: unterminated string interpolation" {
+        t.Fatalf("wrong errors for unterminated interpolation: %q", l.Errors())
+    }
+}
+
+func TestComments(t *testing.T) {
+    input := `// header
+let a = 1; # trailing
+/* block /* nested */ still */ a / /**/ 2 // end`
+
+    tests := []struct {
+        expectedType token.TokenType
+        expectedComments []token.Comment
+    }{
+        {token.LET, []token.Comment{{Text: "// header", Pos: token.Position{Line: 1, Column: 1}, NewLine: true}}},
+        {token.IDENT, nil},
+        {token.ASSIGN, nil},
+        {token.INT, nil},
+        {token.SEMICOLON, nil},
+        {token.IDENT, []token.Comment{
+            {Text: "# trailing", Pos: token.Position{Line: 2, Column: 12}, NewLine: false},
+            {Text: "/* block /* nested */ still */", Pos: token.Position{Line: 3, Column: 1}, NewLine: true},
+        }},
+        {token.SLASH, nil},
+        {token.INT, []token.Comment{{Text: "/**/", Pos: token.Position{Line: 3, Column: 36}, NewLine: false}}},
+        {token.EOF, []token.Comment{{Text: "// end", Pos: token.Position{Line: 3, Column: 43}, NewLine: false}}},
+    }
+
+    l := New(input)
+    for i, tt := range tests {
+        tok := l.NextToken()
+
+        if tok.Type != tt.expectedType {
+            t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+                i, tt.expectedType, tok.Type)
+        }
+        if len(tok.Comments) != len(tt.expectedComments) {
+            t.Fatalf("tests[%d] - comments wrong. expected=%+v, got=%+v",
+                i, tt.expectedComments, tok.Comments)
+        }
+        for j, c := range tok.Comments {
+            if c != tt.expectedComments[j] {
+                t.Errorf("tests[%d] - comment[%d] wrong. expected=%+v, got=%+v",
+                    i, j, tt.expectedComments[j], c)
+            }
+        }
+    }
+
+    l = New("#{1} #{2")
+    if tok := l.NextToken(); tok.Type != token.SET_LBRACE {
+        t.Fatalf("#{ is not SET_LBRACE. got=%q", tok.Type)
+    }
+
+    l = New("1 /* a /* b */")
+    l.NextToken()
+    if tok := l.NextToken(); tok.Type != token.EOF || len(tok.Comments) != 1 {
+        t.Fatalf("unterminated comment not kept. got=%+v", tok)
+    }
+    if len(l.Errors()) != 1 || l.Errors()[0].Error() != "1:3: unterminated block comment" {
+        t.Fatalf("wrong errors for unterminated comment: %q", l.Errors())
+    }
+}
+
+func TestNumbers(t *testing.T) {
+    tests := []struct {
+        input string
+        expectedType token.TokenType
+        expectedError string
+    }{
+        {"1234", token.INT, ""},
+        {"0", token.INT, ""},
+        {"0.5", token.FLOAT, ""},
+        {"007.5", token.FLOAT, ""},
+        {"0e3", token.FLOAT, ""},
+        {"1_000_000", token.INT, ""},
+        {"0x1F", token.INT, ""},
+        {"0XdeAD_beef", token.INT, ""},
+        {"0x_1f", token.INT, ""},
+        {"0o17", token.INT, ""},
+        {"0b1010_1010", token.INT, ""},
+        {"1.5", token.FLOAT, ""},
+        {"1_000.000_1", token.FLOAT, ""},
+        {"1e10", token.FLOAT, ""},
+        {"2.5E-3", token.FLOAT, ""},
+        {"1e+1_0", token.FLOAT, ""},
+        {"0x", token.ILLEGAL, `1:1: invalid number "0x": missing digits after 0x`},
+        {"0b_", token.ILLEGAL, `1:1: invalid number "0b_": missing digits after 0b`},
+        {"1__0", token.ILLEGAL, `1:1: invalid number "1__0": '_' must separate successive digits`},
+        {"10_", token.ILLEGAL, `1:1: invalid number "10_": '_' must separate successive digits`},
+        {"1_.5", token.ILLEGAL, `1:1: invalid number "1_.5": '_' must separate successive digits`},
+        {"0x__1", token.ILLEGAL, `1:1: invalid number "0x__1": '_' must separate successive digits`},
+        {"0b102", token.ILLEGAL, `1:1: invalid number "0b102": invalid digit '2' in binary literal`},
+        {"0o8", token.ILLEGAL, `1:1: invalid number "0o8": invalid digit '8' in octal literal`},
+        {"0xfg", token.ILLEGAL, `1:1: invalid number "0xfg": invalid digit 'g' in hex literal`},
+        {"12ab", token.ILLEGAL, `1:1: invalid number "12ab": invalid digit 'a' in decimal literal`},
+        {"0755", token.ILLEGAL, `1:1: invalid number "0755": leading zero in decimal literal, use 0o for octal`},
+        {"08", token.ILLEGAL, `1:1: invalid number "08": leading zero in decimal literal, use 0o for octal`},
+        {"0_1", token.ILLEGAL, `1:1: invalid number "0_1": leading zero in decimal literal, use 0o for octal`},
+        {"1e", token.ILLEGAL, `1:1: invalid number "1e": exponent has no digits`},
+        {"1.5e-", token.ILLEGAL, `1:1: invalid number "1.5e-": exponent has no digits`},
+    }
+
+    for _, tt := range tests {
+        l := New(tt.input)
+        tok := l.NextToken()
+
+        if tok.Type != tt.expectedType || tok.Literal != tt.input {
+            t.Errorf("%s: token wrong. expected=%q, got=%q (%q)", tt.input, tt.expectedType, tok.Type, tok.Literal)
+        }
+        if tok := l.NextToken(); tok.Type != token.EOF {
+            t.Errorf("%s: expected EOF after number. got=%q", tt.input, tok.Type)
+        }
+        errors := l.Errors()
+        if tt.expectedError == "" && len(errors) != 0 {
+            t.Errorf("%s: unexpected errors: %q", tt.input, errors)
+        }
+        if tt.expectedError != "" && (len(errors) != 1 || errors[0].Error() != tt.expectedError) {
+            t.Errorf("%s: wrong errors. expected=%q, got=%q", tt.input, tt.expectedError, errors)
+        }
+    }
+
+    // a dot without digits after it doesn't belong to the number
+    l := New("1.")
+    if tok := l.NextToken(); tok.Type != token.INT || tok.Literal != "1" {
+        t.Errorf("1.: token wrong. got=%q (%q)", tok.Type, tok.Literal)
+    }
+}
+
+func TestPositions(t *testing.T) {
+    input := "let größe = 1;\n  \"a${b}\" /* x\n */ c\n→"
+
+    tests := []struct {
+        expectedType token.TokenType
+        expectedPos token.Position
+    }{
+        {token.LET, token.Position{Line: 1, Column: 1}},
+        {token.IDENT, token.Position{Line: 1, Column: 5}},
+        {token.ASSIGN, token.Position{Line: 1, Column: 11}},
+        {token.INT, token.Position{Line: 1, Column: 13}},
+        {token.SEMICOLON, token.Position{Line: 1, Column: 14}},
+        {token.TEMPLATE_START, token.Position{Line: 2, Column: 3}},
+        {token.IDENT, token.Position{Line: 2, Column: 7}},
+        {token.TEMPLATE_END, token.Position{Line: 2, Column: 8}},
+        {token.IDENT, token.Position{Line: 3, Column: 5}},
+        {token.ILLEGAL, token.Position{Line: 4, Column: 1}},
+        {token.EOF, token.Position{Line: 4, Column: 2}},
+    }
+
+    l := New(input)
+    for i, tt := range tests {
+        tok := l.NextToken()
+
+        if tok.Type != tt.expectedType {
+            t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+                i, tt.expectedType, tok.Type)
+        }
+        if tok.Pos != tt.expectedPos {
+            t.Errorf("tests[%d] - position wrong. expected=%s, got=%s",
+                i, tt.expectedPos, tok.Pos)
+        }
+        if tt.expectedType == token.IDENT && tok.Literal == "c" {
+            if len(tok.Comments) != 1 || tok.Comments[0].Pos != (token.Position{Line: 2, Column: 11}) {
+                t.Errorf("comment position wrong. got=%+v", tok.Comments)
+            }
+        }
+    }
+
+    errors := l.Errors()
+    if len(errors) != 1 || errors[0].Error() != "4:1: illegal character '→'" {
+        t.Fatalf("wrong errors. got=%q", errors)
+    }
+}
+
+func TestTypeAnnotations(t *testing.T) {
+    input := `fn(a: [int]) -> bool { a - -1 }`
+
+    tests := []struct {
+        expectedType token.TokenType
+        expectedLiteral string
+    }{
+        {token.FUNCTION, "fn"},
+        {token.LPAREN, "("},
+        {token.IDENT, "a"},
+        {token.COLON, ":"},
+        {token.LBRACKET, "["},
+        {token.IDENT, "int"},
+        {token.RBRACKET, "]"},
+        {token.RPAREN, ")"},
+        {token.ARROW, "->"},
+        {token.IDENT, "bool"},
+        {token.LBRACE, "{"},
+        {token.IDENT, "a"},
+        {token.MINUS, "-"},
+        {token.MINUS, "-"},
+        {token.INT, "1"},
+        {token.RBRACE, "}"},
+        {token.EOF, ""},
+    }
+
+    l := New(input)
+    for i, tt := range tests {
+        tok := l.NextToken()
+
+        if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+            t.Fatalf("tests[%d] - token wrong. expected=%q %q, got=%q %q",
+                i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+        }
+    }
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"monkey/lexer"
+	"monkey/lint"
+	"monkey/parser"
+	"os"
+	"strings"
+)
+
+// lintMain runs `monkey lint [-rules r,...] [-disable r,...] [-json] [files]`,
+// which checks the files, or stdin without files, and returns the exit code:
+// 1 if anything was found, 2 on errors
+func lintMain(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	rules := flags.String("rules", "", "comma separated rules to check, all if empty")
+	disable := flags.String("disable", "", "comma separated rules not to check")
+	asJSON := flags.Bool("json", false, "print the findings as a JSON array")
+	list := flags.Bool("list", false, "list the rules and exit")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: monkey lint [-rules r,...] [-disable r,...] [-json] [files]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *list {
+		for _, rule := range lint.Rules {
+			fmt.Printf("%-16s %s\n", rule.Name, rule.Doc)
+		}
+		return 0
+	}
+
+	config := lint.Config{Rules: splitList(*rules), Disabled: splitList(*disable)}
+	if err := config.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "lint:", err)
+		return 2
+	}
+
+	files := flags.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	code := 0
+	findings := []lintFinding{}
+	for _, path := range files {
+		var src []byte
+		var err error
+		if path == "-" {
+			path = "<stdin>"
+			src, err = io.ReadAll(os.Stdin)
+		} else {
+			src, err = os.ReadFile(path)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 2
+			continue
+		}
+
+		program, err := parser.New(lexer.New(string(src))).ParseProgram()
+		if err != nil {
+			for _, e := range err.(parser.ErrorList) {
+				fmt.Fprintf(os.Stderr, "%s:%s\n", path, e)
+			}
+			code = 2
+			continue
+		}
+
+		for _, d := range lint.Lint(program, config) {
+			findings = append(findings, lintFinding{
+				File:    path,
+				Line:    d.Pos.Line,
+				Column:  d.Pos.Column,
+				Rule:    d.Rule,
+				Message: d.Message,
+			})
+			if !*asJSON {
+				fmt.Printf("%s:%s\n", path, d)
+			}
+		}
+	}
+
+	if *asJSON {
+		out, _ := json.MarshalIndent(findings, "", "  ")
+		fmt.Println(string(out))
+	}
+	if code == 0 && len(findings) > 0 {
+		code = 1
+	}
+	return code
+}
+
+// lintFinding is a diagnostic as printed by -json
+type lintFinding struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	items := strings.Split(s, ",")
+	for i, item := range items {
+		items[i] = strings.TrimSpace(item)
+	}
+	return items
+}
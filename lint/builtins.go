@@ -0,0 +1,95 @@
+package lint
+
+import "fmt"
+
+// builtinArity holds the least and the most arguments each builtin accepts,
+// a most of -1 means any number
+var builtinArity = map[string][2]int{
+	"len":  {1, 1},
+	"push": {2, 2},
+	"puts": {0, -1},
+	// strings
+	"split":       {1, 2},
+	"join":        {2, 2},
+	"trim":        {1, 2},
+	"upper":       {1, 1},
+	"lower":       {1, 1},
+	"contains":    {2, 2},
+	"replace":     {3, 3},
+	"index_of":    {2, 2},
+	"substr":      {2, 3},
+	"chars":       {1, 1},
+	"starts_with": {2, 2},
+	"ends_with":   {2, 2},
+	"format":      {1, -1},
+	"str":         {1, 1},
+	"int":         {1, 1},
+	// collections
+	"first":   {1, 1},
+	"last":    {1, 1},
+	"rest":    {1, 1},
+	"map":     {2, 2},
+	"filter":  {2, 2},
+	"reduce":  {3, 3},
+	"sort":    {1, 2},
+	"keys":    {1, 1},
+	"values":  {1, 1},
+	"delete":  {2, 2},
+	"has":     {2, 2},
+	"range":   {1, 3},
+	"zip":     {2, 2},
+	"reverse": {1, 1},
+	"slice":   {2, 3},
+	// math
+	"abs":      {1, 1},
+	"min":      {1, -1},
+	"max":      {1, -1},
+	"pow":      {2, 2},
+	"sqrt":     {1, 1},
+	"floor":    {1, 1},
+	"ceil":     {1, 1},
+	"round":    {1, 1},
+	"clamp":    {3, 3},
+	"sum":      {1, 1},
+	"mean":     {1, 1},
+	"random":   {0, 0},
+	"rand_int": {1, 2},
+	// json
+	"json_parse":     {1, 1},
+	"json_stringify": {1, 2},
+	// sets
+	"set":          {1, 1},
+	"to_array":     {1, 1},
+	"union":        {2, 2},
+	"intersection": {2, 2},
+	"difference":   {2, 2},
+}
+
+// arityMessage describes what goes wrong when builtin name gets got
+// arguments, or returns "" if that's fine
+func arityMessage(name string, got int) string {
+	arity, ok := builtinArity[name]
+	if !ok {
+		return ""
+	}
+	least, most := arity[0], arity[1]
+	if got >= least && (most < 0 || got <= most) {
+		return ""
+	}
+
+	want := fmt.Sprintf("%d to %d arguments", least, most)
+	switch {
+	case most < 0:
+		want = "at least " + arguments(least)
+	case least == most:
+		want = arguments(least)
+	}
+	return fmt.Sprintf("%s takes %s, got %d", name, want, got)
+}
+
+func arguments(n int) string {
+	if n == 1 {
+		return "1 argument"
+	}
+	return fmt.Sprintf("%d arguments", n)
+}
@@ -0,0 +1,349 @@
+// Package lint finds code which compiles, but most likely doesn't do what
+// it's meant to do
+package lint
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/compiler"
+	"monkey/object"
+	"monkey/token"
+	"sort"
+	"strings"
+)
+
+// the names of the rules
+const (
+	UnusedLet     = "unused-let"
+	UnusedParam   = "unused-param"
+	Shadow        = "shadow"
+	BuiltinArity  = "builtin-arity"
+	Unreachable   = "unreachable"
+	IfWithoutElse = "if-without-else"
+)
+
+// Rules lists every rule with a short description
+var Rules = []struct {
+	Name string
+	Doc  string
+}{
+	{UnusedLet, "a let or const inside a function is never used"},
+	{UnusedParam, "a function parameter is never used"},
+	{Shadow, "a binding hides a builtin, an enclosing binding or the name of its function"},
+	{BuiltinArity, "a builtin is called with the wrong number of arguments"},
+	{Unreachable, "a statement follows a return"},
+	{IfWithoutElse, "an if without else is used as a value, which is null when the condition is false"},
+}
+
+// Config selects the rules to check: all of them if Rules is empty, except
+// those in Disabled
+type Config struct {
+	Rules    []string
+	Disabled []string
+}
+
+// Validate returns an error for the first unknown rule name of c
+func (c Config) Validate() error {
+	for _, name := range append(append([]string{}, c.Rules...), c.Disabled...) {
+		if !isRule(name) {
+			return fmt.Errorf("unknown rule %q", name)
+		}
+	}
+	return nil
+}
+
+func (c Config) enabled(rule string) bool {
+	for _, name := range c.Disabled {
+		if name == rule {
+			return false
+		}
+	}
+	if len(c.Rules) == 0 {
+		return true
+	}
+	for _, name := range c.Rules {
+		if name == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func isRule(name string) bool {
+	for _, rule := range Rules {
+		if rule.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+type Diagnostic struct {
+	Pos     token.Position
+	Rule    string
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s (%s)", d.Pos, d.Message, d.Rule)
+}
+
+// Lint checks program with the rules of config and returns what it finds,
+// ordered by position. Bindings at the top level are never reported as
+// unused, they may be used by modules importing the program.
+func Lint(program *ast.Program, config Config) []Diagnostic {
+	table := compiler.NewSymbolTable()
+	for i, v := range object.Builtins {
+		table.DefineBuiltin(i, v.Name)
+	}
+
+	l := &linter{
+		config:    config,
+		scope:     &scope{table: table, bindings: map[string]*binding{}},
+		discarded: map[ast.Node]bool{},
+	}
+	ast.Inspect(program, l.visit)
+
+	sort.SliceStable(l.diagnostics, func(i, j int) bool {
+		a, b := l.diagnostics[i].Pos, l.diagnostics[j].Pos
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+	return l.diagnostics
+}
+
+type binding struct {
+	ident *ast.Identifier
+	param bool
+	used  bool
+}
+
+// scope holds the bindings of a function, or of the top level, next to its
+// symbol table. Like in the compiler, blocks don't have scopes of their own.
+type scope struct {
+	table    *compiler.SymbolTable
+	bindings map[string]*binding
+	order    []*binding
+	outer    *scope
+}
+
+type linter struct {
+	config      Config
+	scope       *scope
+	discarded   map[ast.Node]bool // blocks and expressions whose value isn't used
+	diagnostics []Diagnostic
+}
+
+func (l *linter) report(pos token.Position, rule string, format string, a ...interface{}) {
+	if l.config.enabled(rule) {
+		l.diagnostics = append(l.diagnostics, Diagnostic{
+			Pos:     pos,
+			Rule:    rule,
+			Message: fmt.Sprintf(format, a...),
+		})
+	}
+}
+
+// visit checks node, it walks the children of the nodes binding names
+// itself to keep track of the scopes
+func (l *linter) visit(node ast.Node) bool {
+	switch n := node.(type) {
+	case *ast.Program:
+		l.statements(n.Statements, true)
+	case *ast.BlockStatement:
+		l.statements(n.Statements, l.discarded[n])
+	case *ast.LetStatement:
+		// like in the compiler the name is bound before its value
+		l.define(n.Name, false)
+		if n.Value != nil {
+			ast.Inspect(n.Value, l.visit)
+		}
+		return false
+	case *ast.Identifier:
+		l.use(n.Value)
+	case *ast.IfExpression:
+		if l.discarded[n] {
+			for _, block := range []*ast.BlockStatement{n.Consequence, n.Alternative} {
+				if block != nil {
+					l.discarded[block] = true
+				}
+			}
+		} else if n.Alternative == nil {
+			l.report(n.Token.Pos, IfWithoutElse,
+				"if without else used as a value, it is null when the condition is false")
+		}
+	case *ast.FunctionLiteral:
+		l.function(n.Name, n.Parameters, n.Body)
+		return false
+	case *ast.MacroLiteral:
+		l.function("", n.Parameters, n.Body)
+		return false
+	case *ast.CallExpression:
+		l.call(n)
+	}
+	return true
+}
+
+// statements checks for unreachable code in a block, and marks the values
+// of its statements which are discarded, all but the last one's, which is
+// discarded along with the block
+func (l *linter) statements(stmts []ast.Statement, discarded bool) {
+	terminated := false
+	for _, s := range stmts {
+		if terminated {
+			l.report(statementPos(s), Unreachable, "unreachable code")
+			break
+		}
+		terminated = terminates(s)
+	}
+
+	for i, s := range stmts {
+		last := i == len(stmts)-1
+		switch s := s.(type) {
+		case *ast.ExpressionStatement:
+			l.discarded[s.Expression] = discarded || !last
+		case *ast.BlockStatement:
+			l.discarded[s] = discarded || !last
+		}
+	}
+}
+
+func (l *linter) call(call *ast.CallExpression) {
+	ident, ok := call.Function.(*ast.Identifier)
+	if !ok {
+		return
+	}
+	symbol, ok := l.scope.table.Resolve(ident.Value)
+	if ok && symbol.Scope == compiler.BuiltinScope {
+		if msg := arityMessage(ident.Value, len(call.Arguments)); msg != "" {
+			l.report(ident.Token.Pos, BuiltinArity, "%s", msg)
+		}
+	}
+}
+
+func (l *linter) function(name string, params []*ast.Identifier, body *ast.BlockStatement) {
+	l.scope = &scope{
+		table:    compiler.NewEnclosedSymbolTable(l.scope.table),
+		bindings: map[string]*binding{},
+		outer:    l.scope,
+	}
+	if name != "" {
+		l.scope.table.DefineFunctionName(name)
+	}
+	for _, param := range params {
+		l.define(param, true)
+	}
+	if body != nil {
+		// implicit returns of an if without else are left alone
+		l.discarded[body] = true
+		ast.Inspect(body, l.visit)
+	}
+
+	for _, b := range l.scope.order {
+		if b.used || strings.HasPrefix(b.ident.Value, "_") {
+			continue
+		}
+		if b.param {
+			l.report(b.ident.Token.Pos, UnusedParam, "parameter %s is never used", b.ident.Value)
+		} else {
+			l.report(b.ident.Token.Pos, UnusedLet, "%s declared and never used", b.ident.Value)
+		}
+	}
+	l.scope = l.scope.outer
+}
+
+// define binds ident in the current scope, binding a name again in the same
+// scope reuses its slot, so it counts as the same binding
+func (l *linter) define(ident *ast.Identifier, param bool) {
+	name := ident.Value
+	if _, ok := l.scope.bindings[name]; ok {
+		return
+	}
+
+	table := l.scope.table
+	symbol, ok := table.ResolveLocal(name)
+	own := ok && symbol.Scope == compiler.FunctionScope
+	if (!ok || symbol.Scope == compiler.FreeScope) && table.Outer != nil {
+		symbol, ok = table.Outer.Resolve(name)
+	}
+	switch {
+	case !ok:
+	case own:
+		l.report(ident.Token.Pos, Shadow, "%s shadows the name of its function", name)
+	case symbol.Scope == compiler.BuiltinScope:
+		l.report(ident.Token.Pos, Shadow, "%s shadows the builtin %s", name, name)
+	case symbol.Scope == compiler.FunctionScope:
+		l.report(ident.Token.Pos, Shadow, "%s shadows the name of an enclosing function", name)
+	default:
+		if b := l.scope.outer.lookup(name); b != nil {
+			l.report(ident.Token.Pos, Shadow, "%s shadows the declaration at %s", name, b.ident.Token.Pos)
+		}
+	}
+
+	table.Define(name)
+	b := &binding{ident: ident, param: param}
+	l.scope.bindings[name] = b
+	l.scope.order = append(l.scope.order, b)
+}
+
+// use marks the binding name refers to as used
+func (l *linter) use(name string) {
+	if b := l.scope.lookup(name); b != nil {
+		b.used = true
+	}
+}
+
+// lookup returns the binding name refers to in s, or nil for builtins,
+// function names and undefined names
+func (s *scope) lookup(name string) *binding {
+	for ; s != nil; s = s.outer {
+		if b, ok := s.bindings[name]; ok {
+			return b
+		}
+		// a function's name hides the bindings around it
+		if symbol, ok := s.table.ResolveLocal(name); ok && symbol.Scope == compiler.FunctionScope {
+			return nil
+		}
+	}
+	return nil
+}
+
+// terminates reports whether the statements following s are unreachable
+func terminates(s ast.Statement) bool {
+	switch s := s.(type) {
+	case *ast.ReturnStatement:
+		return true
+	case *ast.ExpressionStatement:
+		ie, ok := s.Expression.(*ast.IfExpression)
+		return ok && ie.Alternative != nil &&
+			blockTerminates(ie.Consequence) && blockTerminates(ie.Alternative)
+	case *ast.BlockStatement:
+		return blockTerminates(s)
+	}
+	return false
+}
+
+func blockTerminates(block *ast.BlockStatement) bool {
+	if block == nil {
+		return false
+	}
+	for _, s := range block.Statements {
+		if terminates(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func statementPos(s ast.Statement) token.Position {
+	switch s := s.(type) {
+	case *ast.LetStatement:
+		return s.Token.Pos
+	case *ast.ReturnStatement:
+		return s.Token.Pos
+	case *ast.ExpressionStatement:
+		return s.Token.Pos
+	case *ast.BlockStatement:
+		return s.Token.Pos
+	}
+	return token.Position{}
+}
@@ -0,0 +1,109 @@
+package lint
+
+import (
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let a = 1; let f = fn(x) { x + a }; f(a)", []string{}},
+		// unused
+		{"let f = fn(x) { let y = 1; x }", []string{"1:21: y declared and never used (unused-let)"}},
+		{"let f = fn(x, y) { x }", []string{"1:15: parameter y is never used (unused-param)"}},
+		{"let f = fn(_x) { let _y = 1; 2 }", []string{}},
+		{"let f = fn() { let y = 1; let y = y + 1; y }", []string{}},
+		{"let f = fn(x) { fn() { x } }", []string{}},
+		{"let f = fn() { let g = fn() { g() }; 1 }", []string{"1:20: g declared and never used (unused-let)"}},
+		{"let m = macro(a) { quote(unquote(a) + 1) }", []string{}},
+		// shadowing
+		{"let a = fn(a) { a }", []string{"1:12: a shadows the name of its function (shadow)"}},
+		{"let a = fn() { let a = 1; a }", []string{"1:20: a shadows the name of its function (shadow)"}},
+		{"let x = 1; let f = fn(x) { x }", []string{"1:23: x shadows the declaration at 1:5 (shadow)"}},
+		{"let f = fn(x) { fn() { let x = 1; x } }", []string{
+			"1:12: parameter x is never used (unused-param)",
+			"1:28: x shadows the declaration at 1:12 (shadow)",
+		}},
+		{"let f = fn() { fn() { let f = 1; f } }", []string{"1:27: f shadows the name of an enclosing function (shadow)"}},
+		{"let len = fn(_s) { 0 }; fn(first) { first }", []string{
+			"1:5: len shadows the builtin len (shadow)",
+			"1:28: first shadows the builtin first (shadow)",
+		}},
+		// builtin arity
+		{"len(1, 2); push([]); puts(); range(1, 2, 3, 4); format()", []string{
+			"1:1: len takes 1 argument, got 2 (builtin-arity)",
+			"1:12: push takes 2 arguments, got 1 (builtin-arity)",
+			"1:30: range takes 1 to 3 arguments, got 4 (builtin-arity)",
+			"1:49: format takes at least 1 argument, got 0 (builtin-arity)",
+		}},
+		{"let f = fn(len) { len(1, 2) }", []string{"1:12: len shadows the builtin len (shadow)"}},
+		// unreachable
+		{"let f = fn(x) { return x; x; 1 }", []string{"1:27: unreachable code (unreachable)"}},
+		{"let f = fn(x) { if (x) { return 1 } else { return 2 }; 3 }", []string{"1:56: unreachable code (unreachable)"}},
+		{"let f = fn(x) { if (x) { return 1 }; 3 }", []string{}},
+		// if without else
+		{"let a = if (true) { 1 };", []string{"1:9: if without else used as a value, it is null when the condition is false (if-without-else)"}},
+		{"puts(if (true) { 1 } else { if (false) { 2 } })", []string{"1:29: if without else used as a value, it is null when the condition is false (if-without-else)"}},
+		{"if (true) { puts(1) }; let f = fn() { if (true) { 1 } }", []string{}},
+	}
+
+	for _, tt := range tests {
+		checkLint(t, tt.input, Config{}, tt.expected)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	input := "let f = fn(x) { let len = 1; return 2; 3 }"
+
+	checkLint(t, input, Config{Rules: []string{Unreachable}}, []string{
+		"1:40: unreachable code (unreachable)",
+	})
+	checkLint(t, input, Config{Disabled: []string{UnusedLet, Shadow}}, []string{
+		"1:12: parameter x is never used (unused-param)",
+		"1:40: unreachable code (unreachable)",
+	})
+
+	if err := (Config{Disabled: []string{"shadows"}}).Validate(); err == nil || err.Error() != `unknown rule "shadows"` {
+		t.Errorf("wrong error for unknown rule, got=%v", err)
+	}
+	if err := (Config{Rules: []string{UnusedLet}, Disabled: []string{Shadow}}).Validate(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestBuiltinArityCoversBuiltins(t *testing.T) {
+	for _, def := range object.Builtins {
+		if _, ok := builtinArity[def.Name]; !ok {
+			t.Errorf("no arity for builtin %s", def.Name)
+		}
+	}
+	if len(builtinArity) != len(object.Builtins) {
+		t.Errorf("arity of unknown builtins, want=%d, got=%d", len(object.Builtins), len(builtinArity))
+	}
+}
+
+func checkLint(t *testing.T, input string, config Config, expected []string) {
+	t.Helper()
+
+	program, err := parser.New(lexer.New(input)).ParseProgram()
+	if err != nil {
+		t.Fatalf("%q: parse error %s", input, err)
+	}
+
+	diagnostics := Lint(program, config)
+	if len(diagnostics) != len(expected) {
+		t.Errorf("%q: wrong number of diagnostics. want=%d, got=%d (%v)",
+			input, len(expected), len(diagnostics), diagnostics)
+		return
+	}
+	for i, d := range diagnostics {
+		if d.String() != expected[i] {
+			t.Errorf("%q: diagnostic %d wrong. want=%q, got=%q", input, i, expected[i], d.String())
+		}
+	}
+}
@@ -2,40 +2,43 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"monkey/compiler"
+	"monkey/evaluator"
 	"monkey/lexer"
+	"monkey/object"
 	"monkey/parser"
 	"monkey/repl"
+	"monkey/types"
 	"monkey/vm"
 	"os"
 	"os/user"
+	"strconv"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "fmt" {
+		os.Exit(fmtMain(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "lint" {
+		os.Exit(lintMain(os.Args[2:]))
+	}
+
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
 	}
-	if len(os.Args) > 1 {
-		line := os.Args[1]
-		l := lexer.New(line)
-		p := parser.New(l)
-		program := p.ParseProgram()
-		fmt.Println(program.String())
-
-		compiler := compiler.New()
-		err := compiler.Compile(program)
+	// reproducible `random` and `rand_int`, e.g. for test runs
+	if seed := os.Getenv("MONKEY_SEED"); seed != "" {
+		n, err := strconv.ParseInt(seed, 10, 64)
 		if err != nil {
-			fmt.Println("=>NIL")
+			fmt.Fprintf(os.Stderr, "invalid MONKEY_SEED %q\n", seed)
+			os.Exit(1)
 		}
-
-		machine := vm.New(compiler.Bytecode())
-		err = machine.Run()
-		if err != nil {
-			fmt.Println("=>NIL")
-		}
-		lastPopped := machine.LastPoppedStackElem()
-		fmt.Println("=>", lastPopped.Inspect())
+		object.SeedRandom(n)
+	}
+	if len(os.Args) > 1 {
+		os.Exit(run(os.Args[1], os.Stdout, os.Stderr))
 	} else {
 		fmt.Printf("Hello %s! This is the monkey programming language!\n",
 			user.Username)
@@ -43,3 +46,64 @@ func main() {
 		repl.Start(os.Stdin, os.Stdout)
 	}
 }
+
+// run expands the macros of source, compiles and runs it, and prints the
+// expanded program and the result to out, errors go to errOut
+func run(source string, out, errOut io.Writer) int {
+	l := lexer.New(source)
+	p := parser.New(l)
+	program, err := p.ParseProgram()
+	if err != nil {
+		for _, e := range p.Errors() {
+			fmt.Fprintln(errOut, e)
+		}
+		return 1
+	}
+
+	macroEnv := object.NewEnvironment()
+	evaluator.DefineMacros(program, macroEnv)
+	if err := evaluator.ExpandMacros(program, macroEnv); err != nil {
+		fmt.Fprintln(errOut, err)
+		return 1
+	}
+	fmt.Fprintln(out, program.String())
+
+	if err := types.Check(program); err != nil {
+		printErrors(errOut, err)
+		return 1
+	}
+
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		printErrors(errOut, err)
+		return 1
+	}
+	for _, w := range comp.Warnings() {
+		fmt.Fprintln(out, "warning:", w)
+	}
+
+	machine := vm.New(comp.Bytecode())
+	err = machine.Run()
+	if err != nil {
+		fmt.Fprintln(out, "=>NIL")
+	}
+	lastPopped := machine.LastPoppedStackElem()
+	fmt.Fprintln(out, "=>", lastPopped.Inspect())
+	return 0
+}
+
+// printErrors prints err to w, an error list one entry per line
+func printErrors(w io.Writer, err error) {
+	switch err := err.(type) {
+	case compiler.ErrorList:
+		for _, e := range err {
+			fmt.Fprintln(w, e)
+		}
+	case types.ErrorList:
+		for _, e := range err {
+			fmt.Fprintln(w, e)
+		}
+	default:
+		fmt.Fprintln(w, err)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		input  string
+		code   int
+		out    string
+		errOut string
+	}{
+		{"1 + 2", 0, "(1 + 2)\n=> 3\n", ""},
+		{
+			// macros are expanded before the program gets compiled
+			"let unless = macro(c, a, b) { quote(if (!(unquote(c))) { unquote(a) } else { unquote(b) }) }; unless(10 > 5, 1, 2)",
+			0,
+			"if (!(10 > 5)) {1} else {2}\n=> 2\n",
+			"",
+		},
+		{
+			"let m = macro(x) { 1 }; m(1, 2)",
+			1,
+			"",
+			"1:25: macro m: wrong number of arguments: want=1, got=2\n",
+		},
+		{"let x = ;", 1, "", "1:9: no prefix parse function for ; found\n"},
+	}
+
+	for _, tt := range tests {
+		var out, errOut strings.Builder
+		code := run(tt.input, &out, &errOut)
+		if code != tt.code {
+			t.Errorf("%q: wrong exit code. want=%d, got=%d", tt.input, tt.code, code)
+		}
+		if out.String() != tt.out {
+			t.Errorf("%q: wrong output. want=%q, got=%q", tt.input, tt.out, out.String())
+		}
+		if errOut.String() != tt.errOut {
+			t.Errorf("%q: wrong errors. want=%q, got=%q", tt.input, tt.errOut, errOut.String())
+		}
+	}
+}
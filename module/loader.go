@@ -0,0 +1,123 @@
+package module
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Ext is tried as suffix when an import path doesn't name a file directly
+const Ext = ".monkey"
+
+// ExecFunc runs the program of a module in its own global namespace and
+// returns its top-level bindings. Imports done by the module go through the
+// same loader.
+type ExecFunc func(l *Loader, program *ast.Program) (*object.Hash, error)
+
+// Loader resolves import paths along a search path, and makes sure every
+// module source file is loaded only once.
+type Loader struct {
+	SearchPath []string
+
+	exec    ExecFunc
+	cache   map[string]*object.Module // key: absolute path of source file
+	loading []string                  // absolute paths of modules being loaded
+}
+
+func NewLoader(exec ExecFunc, searchPath ...string) *Loader {
+	return &Loader{
+		SearchPath: searchPath,
+		exec:       exec,
+		cache:      make(map[string]*object.Module),
+		loading:    []string{},
+	}
+}
+
+// DefaultSearchPath is the current directory followed by the directories
+// listed in $MONKEY_PATH
+func DefaultSearchPath() []string {
+	searchPath := []string{"."}
+	for _, dir := range filepath.SplitList(os.Getenv("MONKEY_PATH")) {
+		if dir != "" {
+			searchPath = append(searchPath, dir)
+		}
+	}
+	return searchPath
+}
+
+func (l *Loader) Load(name string) (*object.Module, error) {
+	path, err := l.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if mod, ok := l.cache[path]; ok {
+		return mod, nil
+	}
+
+	for i, loading := range l.loading {
+		if loading == path {
+			cycle := append(append([]string{}, l.loading[i:]...), path)
+			return nil, fmt.Errorf("import cycle detected: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	l.loading = append(l.loading, path)
+	defer func() {
+		l.loading = l.loading[:len(l.loading)-1]
+	}()
+
+	program, err := parseFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	exports, err := l.exec(l, program)
+	if err != nil {
+		return nil, fmt.Errorf("module %q: %s", name, err)
+	}
+
+	mod := &object.Module{Name: name, Exports: exports}
+	l.cache[path] = mod
+	return mod, nil
+}
+
+func (l *Loader) resolve(name string) (string, error) {
+	candidates := []string{}
+	if filepath.IsAbs(name) {
+		candidates = append(candidates, name)
+	} else {
+		for _, dir := range l.SearchPath {
+			candidates = append(candidates, filepath.Join(dir, name))
+		}
+	}
+
+	for _, c := range candidates {
+		for _, path := range []string{c, c + Ext} {
+			info, err := os.Stat(path)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			return filepath.Abs(path)
+		}
+	}
+	return "", fmt.Errorf("module %q not found in search path %v", name, l.SearchPath)
+}
+
+func parseFile(path string) (*ast.Program, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser.New(lexer.New(string(src)))
+	program, err := p.ParseProgram()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return program, nil
+}
@@ -2,6 +2,7 @@ package object
 
 import (
 	"fmt"
+	"unicode/utf8"
 )
 
 func newError(format string, a ...interface{}) *Error {
@@ -21,9 +22,11 @@ var Builtins = []struct {
 				}
 				switch arg := args[0].(type) {
 				case *String:
-					return &Integer{Value: int64(len(arg.Value))}
+					return &Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
 				case *Array:
 					return &Integer{Value: int64(len(arg.Elements))}
+				case *Set:
+					return &Integer{Value: int64(arg.Len())}
 				default:
 					return newError("argument to `len` not supported, got %s",
 						args[0].Type())
@@ -63,6 +66,61 @@ var Builtins = []struct {
 			},
 		},
 	},
+	// strings
+	{"split", &Builtin{Fn: builtinSplit}},
+	{"join", &Builtin{Fn: builtinJoin}},
+	{"trim", &Builtin{Fn: builtinTrim}},
+	{"upper", &Builtin{Fn: builtinUpper}},
+	{"lower", &Builtin{Fn: builtinLower}},
+	{"contains", &Builtin{Fn: builtinContains}},
+	{"replace", &Builtin{Fn: builtinReplace}},
+	{"index_of", &Builtin{Fn: builtinIndexOf}},
+	{"substr", &Builtin{Fn: builtinSubstr}},
+	{"chars", &Builtin{Fn: builtinChars}},
+	{"starts_with", &Builtin{Fn: builtinStartsWith}},
+	{"ends_with", &Builtin{Fn: builtinEndsWith}},
+	{"format", &Builtin{Fn: builtinFormat}},
+	{"str", &Builtin{Fn: builtinStr}},
+	{"int", &Builtin{Fn: builtinInt}},
+	// collections
+	{"first", &Builtin{Fn: builtinFirst}},
+	{"last", &Builtin{Fn: builtinLast}},
+	{"rest", &Builtin{Fn: builtinRest}},
+	{"map", &Builtin{HigherOrderFn: builtinMap}},
+	{"filter", &Builtin{HigherOrderFn: builtinFilter}},
+	{"reduce", &Builtin{HigherOrderFn: builtinReduce}},
+	{"sort", &Builtin{HigherOrderFn: builtinSort}},
+	{"keys", &Builtin{Fn: builtinKeys}},
+	{"values", &Builtin{Fn: builtinValues}},
+	{"delete", &Builtin{Fn: builtinDelete}},
+	{"has", &Builtin{Fn: builtinHas}},
+	{"range", &Builtin{Fn: builtinRange}},
+	{"zip", &Builtin{Fn: builtinZip}},
+	{"reverse", &Builtin{Fn: builtinReverse}},
+	{"slice", &Builtin{Fn: builtinSlice}},
+	// math
+	{"abs", &Builtin{Fn: builtinAbs}},
+	{"min", &Builtin{Fn: builtinMin}},
+	{"max", &Builtin{Fn: builtinMax}},
+	{"pow", &Builtin{Fn: builtinPow}},
+	{"sqrt", &Builtin{Fn: builtinSqrt}},
+	{"floor", &Builtin{Fn: builtinFloor}},
+	{"ceil", &Builtin{Fn: builtinCeil}},
+	{"round", &Builtin{Fn: builtinRound}},
+	{"clamp", &Builtin{Fn: builtinClamp}},
+	{"sum", &Builtin{Fn: builtinSum}},
+	{"mean", &Builtin{Fn: builtinMean}},
+	{"random", &Builtin{Fn: builtinRandom}},
+	{"rand_int", &Builtin{Fn: builtinRandInt}},
+	// json
+	{"json_parse", &Builtin{Fn: builtinJSONParse}},
+	{"json_stringify", &Builtin{Fn: builtinJSONStringify}},
+	// sets
+	{"set", &Builtin{Fn: builtinSet}},
+	{"to_array", &Builtin{Fn: builtinToArray}},
+	{"union", &Builtin{Fn: builtinUnion}},
+	{"intersection", &Builtin{Fn: builtinIntersection}},
+	{"difference", &Builtin{Fn: builtinDifference}},
 }
 
 func GetBuiltinByName(name string) *Builtin {
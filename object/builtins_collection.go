@@ -0,0 +1,405 @@
+package object
+
+import (
+	"sort"
+)
+
+func builtinFirst(args ...Object) Object {
+	if err := expectArgs("first", args, ARRAY_OBJ); err != nil {
+		return err
+	}
+	elements := args[0].(*Array).Elements
+	if len(elements) == 0 {
+		return NULL
+	}
+	return elements[0]
+}
+
+func builtinLast(args ...Object) Object {
+	if err := expectArgs("last", args, ARRAY_OBJ); err != nil {
+		return err
+	}
+	elements := args[0].(*Array).Elements
+	if len(elements) == 0 {
+		return NULL
+	}
+	return elements[len(elements)-1]
+}
+
+func builtinRest(args ...Object) Object {
+	if err := expectArgs("rest", args, ARRAY_OBJ); err != nil {
+		return err
+	}
+	elements := args[0].(*Array).Elements
+	if len(elements) == 0 {
+		return NULL
+	}
+	rest := make([]Object, len(elements)-1)
+	copy(rest, elements[1:])
+	return &Array{Elements: rest}
+}
+
+func builtinMap(call CallFunction, args ...Object) Object {
+	if err := expectCallbackArgs("map", args, ARRAY_OBJ); err != nil {
+		return err
+	}
+	elements := args[0].(*Array).Elements
+
+	result := make([]Object, len(elements))
+	for i, e := range elements {
+		mapped := call(args[1], e)
+		if isError(mapped) {
+			return mapped
+		}
+		result[i] = mapped
+	}
+	return &Array{Elements: result}
+}
+
+func builtinFilter(call CallFunction, args ...Object) Object {
+	if err := expectCallbackArgs("filter", args, ARRAY_OBJ); err != nil {
+		return err
+	}
+	elements := args[0].(*Array).Elements
+
+	result := []Object{}
+	for _, e := range elements {
+		keep := call(args[1], e)
+		if isError(keep) {
+			return keep
+		}
+		if isTruthy(keep) {
+			result = append(result, e)
+		}
+	}
+	return &Array{Elements: result}
+}
+
+// builtinReduce folds the array from left to right: reduce(arr, initial, fn(acc, e))
+func builtinReduce(call CallFunction, args ...Object) Object {
+	if len(args) != 3 {
+		return newError("wrong number of arguments, got=%d, want=3", len(args))
+	}
+	if err := expectCallbackArgs("reduce", []Object{args[0], args[2]}, ARRAY_OBJ); err != nil {
+		return err
+	}
+
+	acc := args[1]
+	for _, e := range args[0].(*Array).Elements {
+		acc = call(args[2], acc, e)
+		if isError(acc) {
+			return acc
+		}
+	}
+	return acc
+}
+
+// builtinSort returns a sorted copy of an array of integers or strings, an
+// optional comparator fn(a, b) returns either whether a < b, or an integer
+// which is negative when a < b
+func builtinSort(call CallFunction, args ...Object) Object {
+	if len(args) == 2 {
+		if err := expectCallbackArgs("sort", args, ARRAY_OBJ); err != nil {
+			return err
+		}
+	} else if err := expectArgs("sort", args, ARRAY_OBJ); err != nil {
+		return err
+	}
+
+	elements := args[0].(*Array).Elements
+	sorted := make([]Object, len(elements))
+	copy(sorted, elements)
+
+	var err Object
+	less := func(a, b Object) bool {
+		if err != nil {
+			return false
+		}
+
+		var result Object
+		if len(args) == 2 {
+			result = call(args[1], a, b)
+		} else {
+			result = compareObjects(a, b)
+		}
+
+		if result == nil {
+			result = NULL
+		}
+		switch result := result.(type) {
+		case *Boolean:
+			return result.Value
+		case *Integer:
+			return result.Value < 0
+		case *Error:
+			err = result
+		default:
+			err = newError("comparator of `sort` must return BOOLEAN or INTEGER, got %s", result.Type())
+		}
+		return false
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return less(sorted[i], sorted[j])
+	})
+
+	if err != nil {
+		return err
+	}
+	return &Array{Elements: sorted}
+}
+
+// compareObjects compares integers or strings, the result is negative, zero
+// or positive like strings.Compare
+func compareObjects(a, b Object) Object {
+	switch {
+	case a.Type() == INTEGER_OBJ && b.Type() == INTEGER_OBJ:
+		av, bv := a.(*Integer).Value, b.(*Integer).Value
+		switch {
+		case av < bv:
+			return &Integer{Value: -1}
+		case av > bv:
+			return &Integer{Value: 1}
+		}
+		return &Integer{Value: 0}
+	case a.Type() == STRING_OBJ && b.Type() == STRING_OBJ:
+		av, bv := a.(*String).Value, b.(*String).Value
+		switch {
+		case av < bv:
+			return &Integer{Value: -1}
+		case av > bv:
+			return &Integer{Value: 1}
+		}
+		return &Integer{Value: 0}
+	default:
+		return newError("cannot compare %s with %s", a.Type(), b.Type())
+	}
+}
+
+func builtinKeys(args ...Object) Object {
+	if err := expectArgs("keys", args, HASH_OBJ); err != nil {
+		return err
+	}
+	pairs := args[0].(*Hash).Pairs()
+
+	keys := make([]Object, len(pairs))
+	for i, pair := range pairs {
+		keys[i] = pair.Key
+	}
+	return &Array{Elements: keys}
+}
+
+func builtinValues(args ...Object) Object {
+	if err := expectArgs("values", args, HASH_OBJ); err != nil {
+		return err
+	}
+	pairs := args[0].(*Hash).Pairs()
+
+	values := make([]Object, len(pairs))
+	for i, pair := range pairs {
+		values[i] = pair.Value
+	}
+	return &Array{Elements: values}
+}
+
+// builtinDelete returns a copy of the hash without the given key
+func builtinDelete(args ...Object) Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments, got=%d, want=2", len(args))
+	}
+	hash, ok := args[0].(*Hash)
+	if !ok {
+		return newError("argument 1 to `delete` must be HASH, got %s", args[0].Type())
+	}
+	key, ok := AsHashable(args[1])
+	if !ok {
+		return newError("unusable as hash key: %s", args[1].Type())
+	}
+
+	result := NewHash()
+	for _, pair := range hash.Pairs() {
+		if !key.KeyEquals(pair.Key.(Hashable)) {
+			result.Set(pair.Key.(Hashable), pair.Value)
+		}
+	}
+	return result
+}
+
+func builtinHas(args ...Object) Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments, got=%d, want=2", len(args))
+	}
+	hash, ok := args[0].(*Hash)
+	if !ok {
+		return newError("argument 1 to `has` must be HASH, got %s", args[0].Type())
+	}
+	key, ok := AsHashable(args[1])
+	if !ok {
+		return newError("unusable as hash key: %s", args[1].Type())
+	}
+
+	_, ok = hash.Get(key)
+	return NativeBoolToBooleanObject(ok)
+}
+
+// builtinRange returns the integers from start up to, but excluding end:
+// range(end), range(start, end) or range(start, end, step)
+func builtinRange(args ...Object) Object {
+	bounds := []int64{0, 0, 1}
+	switch len(args) {
+	case 1:
+		if err := expectArgs("range", args, INTEGER_OBJ); err != nil {
+			return err
+		}
+		bounds[1] = args[0].(*Integer).Value
+	case 2, 3:
+		for i, arg := range args {
+			integer, ok := arg.(*Integer)
+			if !ok {
+				return newError("argument %d to `range` must be INTEGER, got %s", i+1, arg.Type())
+			}
+			bounds[i] = integer.Value
+		}
+	default:
+		return newError("wrong number of arguments, got=%d, want=1..3", len(args))
+	}
+
+	start, end, step := bounds[0], bounds[1], bounds[2]
+	if step == 0 {
+		return newError("step to `range` must not be zero")
+	}
+
+	n := rangeLength(start, end, step)
+	if n > maxRangeLength {
+		return newError("`range` of %d elements is too large, at most %d", n, maxRangeLength)
+	}
+	elements := make([]Object, n)
+	for i, value := 0, start; i < len(elements); i, value = i+1, value+step {
+		elements[i] = &Integer{Value: value}
+	}
+	return &Array{Elements: elements}
+}
+
+// maxRangeLength limits the arrays `range` builds
+const maxRangeLength = 1 << 24
+
+// rangeLength counts the integers from start up to, but not including, end
+// by step, in uint64 so that nothing overflows
+func rangeLength(start, end, step int64) uint64 {
+	var distance, by uint64
+	switch {
+	case step > 0 && start < end:
+		distance, by = uint64(end)-uint64(start), uint64(step)
+	case step < 0 && start > end:
+		distance, by = uint64(start)-uint64(end), uint64(-(step+1))+1
+	default:
+		return 0
+	}
+	return (distance-1)/by + 1
+}
+
+// builtinZip pairs up the elements of two arrays, up to the shorter one
+func builtinZip(args ...Object) Object {
+	if err := expectArgs("zip", args, ARRAY_OBJ, ARRAY_OBJ); err != nil {
+		return err
+	}
+	a, b := args[0].(*Array).Elements, args[1].(*Array).Elements
+
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	elements := make([]Object, n)
+	for i := 0; i < n; i++ {
+		elements[i] = &Array{Elements: []Object{a[i], b[i]}}
+	}
+	return &Array{Elements: elements}
+}
+
+func builtinReverse(args ...Object) Object {
+	if err := expectArgs("reverse", args, ARRAY_OBJ); err != nil {
+		return err
+	}
+	elements := args[0].(*Array).Elements
+
+	reversed := make([]Object, len(elements))
+	for i, e := range elements {
+		reversed[len(elements)-1-i] = e
+	}
+	return &Array{Elements: reversed}
+}
+
+// builtinSlice returns the elements from start up to, but excluding end, or
+// all the rest if end is missing. Negative indexes count from the end.
+func builtinSlice(args ...Object) Object {
+	var err *Error
+	if len(args) == 2 {
+		err = expectArgs("slice", args, ARRAY_OBJ, INTEGER_OBJ)
+	} else {
+		err = expectArgs("slice", args, ARRAY_OBJ, INTEGER_OBJ, INTEGER_OBJ)
+	}
+	if err != nil {
+		return err
+	}
+
+	elements := args[0].(*Array).Elements
+	length := int64(len(elements))
+	start := sliceBound(args[1].(*Integer).Value, length)
+	end := length
+	if len(args) == 3 {
+		end = sliceBound(args[2].(*Integer).Value, length)
+	}
+	if end < start {
+		end = start
+	}
+
+	sliced := make([]Object, end-start)
+	copy(sliced, elements[start:end])
+	return &Array{Elements: sliced}
+}
+
+// sliceBound turns a possibly negative index into one within [0, length]
+func sliceBound(index, length int64) int64 {
+	if index < 0 {
+		index += length
+	}
+	return clamp(index, 0, length)
+}
+
+// expectCallbackArgs checks the arguments of builtins taking a collection
+// and a function to call on its elements
+func expectCallbackArgs(name string, args []Object, collection ObjectType) *Error {
+	if len(args) != 2 {
+		return newError("wrong number of arguments, got=%d, want=2", len(args))
+	}
+	if args[0].Type() != collection {
+		return newError("argument 1 to `%s` must be %s, got %s", name, collection, args[0].Type())
+	}
+	if !isCallable(args[1]) {
+		return newError("argument 2 to `%s` must be FUNCTION, got %s", name, args[1].Type())
+	}
+	return nil
+}
+
+func isCallable(obj Object) bool {
+	switch obj.Type() {
+	case CLOSURE_OBJ, FUNCTION_OBJ, BUILTIN_OBJ:
+		return true
+	default:
+		return false
+	}
+}
+
+func isTruthy(obj Object) bool {
+	switch obj := obj.(type) {
+	case *Boolean:
+		return obj.Value
+	case *Null:
+		return false
+	default:
+		return true
+	}
+}
+
+func isError(obj Object) bool {
+	return obj != nil && obj.Type() == ERROR_OBJ
+}
@@ -0,0 +1,188 @@
+package object
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func builtinJSONParse(args ...Object) Object {
+	if err := expectArgs("json_parse", args, STRING_OBJ); err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(strings.NewReader(args[0].(*String).Value))
+	dec.UseNumber()
+
+	obj, err := decodeJSON(dec)
+	if err == nil {
+		// nothing may follow the value
+		if _, err = dec.Token(); err == io.EOF {
+			return obj
+		} else if err == nil {
+			err = fmt.Errorf("unexpected data after top-level value")
+		}
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return newError("invalid JSON: %s", err)
+}
+
+// decodeJSON reads the next value token by token, so that object keys keep
+// their order
+func decodeJSON(dec *json.Decoder) (Object, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '[' {
+			elements := []Object{}
+			for dec.More() {
+				e, err := decodeJSON(dec)
+				if err != nil {
+					return nil, err
+				}
+				elements = append(elements, e)
+			}
+			_, err := dec.Token() // the ']'
+			return &Array{Elements: elements}, err
+		}
+
+		hash := NewHash()
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			hash.Set(&String{Value: keyTok.(string)}, value)
+		}
+		_, err := dec.Token() // the '}'
+		return hash, err
+	case string:
+		return &String{Value: tok}, nil
+	case json.Number:
+		if i, err := tok.Int64(); err == nil {
+			return &Integer{Value: i}, nil
+		}
+		f, err := tok.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return &Float{Value: f}, nil
+	case bool:
+		return NativeBoolToBooleanObject(tok), nil
+	default:
+		return NULL, nil
+	}
+}
+
+// builtinJSONStringify encodes a value as JSON with hash keys in sorted
+// order. An optional indent is either a number of spaces or a string.
+func builtinJSONStringify(args ...Object) Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments, got=%d, want=1..2", len(args))
+	}
+
+	var out bytes.Buffer
+	if err := encodeJSON(&out, args[0]); err != nil {
+		return newError("%s", err)
+	}
+	if len(args) == 1 {
+		return &String{Value: out.String()}
+	}
+
+	var indent string
+	switch arg := args[1].(type) {
+	case *Integer:
+		indent = strings.Repeat(" ", int(clamp(arg.Value, 0, 16)))
+	case *String:
+		indent = arg.Value
+	default:
+		return newError("argument 2 to `json_stringify` must be INTEGER or STRING, got %s", arg.Type())
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, out.Bytes(), "", indent); err != nil {
+		return newError("%s", err)
+	}
+	return &String{Value: indented.String()}
+}
+
+func encodeJSON(out *bytes.Buffer, obj Object) error {
+	switch obj := obj.(type) {
+	case *Null:
+		out.WriteString("null")
+	case *Boolean:
+		out.WriteString(strconv.FormatBool(obj.Value))
+	case *Integer:
+		out.WriteString(strconv.FormatInt(obj.Value, 10))
+	case *Float:
+		if math.IsInf(obj.Value, 0) || math.IsNaN(obj.Value) {
+			return fmt.Errorf("unsupported value for JSON: %s", obj.Inspect())
+		}
+		out.WriteString(obj.Inspect())
+	case *String:
+		encodeJSONString(out, obj.Value)
+	case *Array:
+		out.WriteString("[")
+		for i, e := range obj.Elements {
+			if i > 0 {
+				out.WriteString(",")
+			}
+			if err := encodeJSON(out, e); err != nil {
+				return err
+			}
+		}
+		out.WriteString("]")
+	case *Hash:
+		// JSON keys are strings, other keys are written like they inspect
+		// as long as that doesn't collide with another key
+		keys := make([]string, 0, obj.Len())
+		values := make(map[string]Object, obj.Len())
+		for _, pair := range obj.Pairs() {
+			key := pair.Key.Inspect()
+			if _, ok := values[key]; ok {
+				return fmt.Errorf("duplicate JSON key %q", key)
+			}
+			keys = append(keys, key)
+			values[key] = pair.Value
+		}
+		sort.Strings(keys)
+
+		out.WriteString("{")
+		for i, key := range keys {
+			if i > 0 {
+				out.WriteString(",")
+			}
+			encodeJSONString(out, key)
+			out.WriteString(":")
+			if err := encodeJSON(out, values[key]); err != nil {
+				return err
+			}
+		}
+		out.WriteString("}")
+	default:
+		return fmt.Errorf("unsupported type for JSON: %s", obj.Type())
+	}
+	return nil
+}
+
+func encodeJSONString(out *bytes.Buffer, s string) {
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	out.Truncate(out.Len() - 1) // Encode adds a newline
+}
@@ -0,0 +1,314 @@
+package object
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// rng backs `random` and `rand_int`, it's shared by all VMs and evaluators
+// so it's only used with rngMu held
+var (
+	rng   = rand.New(rand.NewSource(time.Now().UnixNano()))
+	rngMu sync.Mutex
+)
+
+// SeedRandom makes `random` and `rand_int` produce a reproducible sequence,
+// e.g. for test runs
+func SeedRandom(seed int64) {
+	rngMu.Lock()
+	defer rngMu.Unlock()
+	rng = rand.New(rand.NewSource(seed))
+}
+
+func randomFloat() float64 {
+	rngMu.Lock()
+	defer rngMu.Unlock()
+	return rng.Float64()
+}
+
+// randomBelow returns a uniformly distributed integer in [0, n)
+func randomBelow(n uint64) uint64 {
+	rngMu.Lock()
+	defer rngMu.Unlock()
+	if n <= math.MaxInt64 {
+		return uint64(rng.Int63n(int64(n)))
+	}
+	// more than half of all values are below n
+	for {
+		if v := rng.Uint64(); v < n {
+			return v
+		}
+	}
+}
+
+// ToFloat converts an integer or float object to a native float
+func ToFloat(obj Object) (float64, bool) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return float64(obj.Value), true
+	case *Float:
+		return obj.Value, true
+	default:
+		return 0, false
+	}
+}
+
+func isNumber(obj Object) bool {
+	return obj.Type() == INTEGER_OBJ || obj.Type() == FLOAT_OBJ
+}
+
+// expectNumbers checks that all arguments of builtin name are numbers
+func expectNumbers(name string, args []Object, want int) *Error {
+	if len(args) != want {
+		return newError("wrong number of arguments, got=%d, want=%d", len(args), want)
+	}
+	for i, arg := range args {
+		if !isNumber(arg) {
+			return newError("argument %d to `%s` must be INTEGER or FLOAT, got %s",
+				i+1, name, arg.Type())
+		}
+	}
+	return nil
+}
+
+func builtinAbs(args ...Object) Object {
+	if err := expectNumbers("abs", args, 1); err != nil {
+		return err
+	}
+	switch arg := args[0].(type) {
+	case *Integer:
+		if arg.Value < 0 {
+			return &Integer{Value: -arg.Value}
+		}
+		return arg
+	default:
+		return &Float{Value: math.Abs(arg.(*Float).Value)}
+	}
+}
+
+func builtinMin(args ...Object) Object {
+	return extremum("min", args, func(a, b float64) bool { return a < b })
+}
+
+func builtinMax(args ...Object) Object {
+	return extremum("max", args, func(a, b float64) bool { return a > b })
+}
+
+// extremum returns the number of either the arguments, or of a single array
+// argument, which wins the comparison with all the others
+func extremum(name string, args []Object, wins func(a, b float64) bool) Object {
+	if len(args) == 1 && args[0].Type() == ARRAY_OBJ {
+		args = args[0].(*Array).Elements
+	}
+	if len(args) == 0 {
+		return newError("`%s` needs at least one number", name)
+	}
+	if err := expectNumbers(name, args, len(args)); err != nil {
+		return err
+	}
+
+	result := args[0]
+	for _, arg := range args[1:] {
+		a, _ := ToFloat(arg)
+		r, _ := ToFloat(result)
+		if wins(a, r) {
+			result = arg
+		}
+	}
+	return result
+}
+
+// builtinPow keeps integers when raising an integer to a non-negative integer,
+// unless the result overflows
+func builtinPow(args ...Object) Object {
+	if err := expectNumbers("pow", args, 2); err != nil {
+		return err
+	}
+
+	base, baseIsInt := args[0].(*Integer)
+	exp, expIsInt := args[1].(*Integer)
+	if baseIsInt && expIsInt && exp.Value >= 0 {
+		if result, ok := intPow(base.Value, exp.Value); ok {
+			return &Integer{Value: result}
+		}
+	}
+
+	x, _ := ToFloat(args[0])
+	y, _ := ToFloat(args[1])
+	return &Float{Value: math.Pow(x, y)}
+}
+
+// intPow raises base to exp by squaring, ok is false if it overflows
+func intPow(base, exp int64) (result int64, ok bool) {
+	result = 1
+	for exp > 0 {
+		if exp&1 == 1 {
+			if result, ok = mulInt(result, base); !ok {
+				return 0, false
+			}
+		}
+		exp >>= 1
+		if exp > 0 {
+			if base, ok = mulInt(base, base); !ok {
+				return 0, false
+			}
+		}
+	}
+	return result, true
+}
+
+// mulInt multiplies a and b, ok is false if it overflows
+func mulInt(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	c := a * b
+	if c/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+	return c, true
+}
+
+func builtinSqrt(args ...Object) Object {
+	if err := expectNumbers("sqrt", args, 1); err != nil {
+		return err
+	}
+	x, _ := ToFloat(args[0])
+	if x < 0 {
+		return newError("argument to `sqrt` must not be negative, got %s", args[0].Inspect())
+	}
+	return &Float{Value: math.Sqrt(x)}
+}
+
+func builtinFloor(args ...Object) Object {
+	return roundWith("floor", args, math.Floor)
+}
+
+func builtinCeil(args ...Object) Object {
+	return roundWith("ceil", args, math.Ceil)
+}
+
+// builtinRound rounds half away from zero
+func builtinRound(args ...Object) Object {
+	return roundWith("round", args, math.Round)
+}
+
+func roundWith(name string, args []Object, round func(float64) float64) Object {
+	if err := expectNumbers(name, args, 1); err != nil {
+		return err
+	}
+	if args[0].Type() == INTEGER_OBJ {
+		return args[0]
+	}
+
+	x := round(args[0].(*Float).Value)
+	switch {
+	case math.IsNaN(x) || math.IsInf(x, 0):
+		return newError("argument to `%s` must be finite, got %s", name, args[0].Inspect())
+	case x < math.MinInt64 || x >= math.MaxInt64:
+		return newError("argument to `%s` out of INTEGER range, got %s", name, args[0].Inspect())
+	}
+	return &Integer{Value: int64(x)}
+}
+
+// builtinClamp limits a number to the range [min, max]: clamp(x, min, max)
+func builtinClamp(args ...Object) Object {
+	if err := expectNumbers("clamp", args, 3); err != nil {
+		return err
+	}
+	x, _ := ToFloat(args[0])
+	lo, _ := ToFloat(args[1])
+	hi, _ := ToFloat(args[2])
+	if lo > hi {
+		return newError("bounds to `clamp` are reversed: %s > %s", args[1].Inspect(), args[2].Inspect())
+	}
+
+	switch {
+	case x < lo:
+		return args[1]
+	case x > hi:
+		return args[2]
+	default:
+		return args[0]
+	}
+}
+
+// builtinSum adds up an array of numbers, it stays an integer unless there
+// is a float among them
+func builtinSum(args ...Object) Object {
+	if err := expectArgs("sum", args, ARRAY_OBJ); err != nil {
+		return err
+	}
+	elements := args[0].(*Array).Elements
+	if err := expectNumbers("sum", elements, len(elements)); err != nil {
+		return newError("elements to `sum` must be INTEGER or FLOAT")
+	}
+
+	var intSum int64
+	var floatSum float64
+	isFloat := false
+	for _, e := range elements {
+		switch e := e.(type) {
+		case *Integer:
+			intSum += e.Value
+		case *Float:
+			floatSum += e.Value
+			isFloat = true
+		}
+	}
+	if isFloat {
+		return &Float{Value: floatSum + float64(intSum)}
+	}
+	return &Integer{Value: intSum}
+}
+
+func builtinMean(args ...Object) Object {
+	if err := expectArgs("mean", args, ARRAY_OBJ); err != nil {
+		return err
+	}
+	count := len(args[0].(*Array).Elements)
+	if count == 0 {
+		return newError("`mean` of empty array")
+	}
+
+	sum := builtinSum(args...)
+	if isError(sum) {
+		return newError("elements to `mean` must be INTEGER or FLOAT")
+	}
+	total, _ := ToFloat(sum)
+	return &Float{Value: total / float64(count)}
+}
+
+// builtinRandom returns a float in [0.0, 1.0)
+func builtinRandom(args ...Object) Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments, got=%d, want=0", len(args))
+	}
+	return &Float{Value: randomFloat()}
+}
+
+// builtinRandInt returns an integer in [0, n) for rand_int(n), or in
+// [min, max) for rand_int(min, max)
+func builtinRandInt(args ...Object) Object {
+	var min, max int64
+	switch len(args) {
+	case 1:
+		if err := expectArgs("rand_int", args, INTEGER_OBJ); err != nil {
+			return err
+		}
+		max = args[0].(*Integer).Value
+	default:
+		if err := expectArgs("rand_int", args, INTEGER_OBJ, INTEGER_OBJ); err != nil {
+			return err
+		}
+		min, max = args[0].(*Integer).Value, args[1].(*Integer).Value
+	}
+
+	if max <= min {
+		return newError("empty range for `rand_int`: [%d, %d)", min, max)
+	}
+	// the span may not fit in an int64, the sum wraps back into range
+	return &Integer{Value: min + int64(randomBelow(uint64(max)-uint64(min)))}
+}
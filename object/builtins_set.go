@@ -0,0 +1,55 @@
+package object
+
+// builtinSet makes a set of the elements of an array
+func builtinSet(args ...Object) Object {
+	if err := expectArgs("set", args, ARRAY_OBJ); err != nil {
+		return err
+	}
+
+	set := NewSet()
+	for _, e := range args[0].(*Array).Elements {
+		key, ok := AsHashable(e)
+		if !ok {
+			return newError("unusable as set element: %s", e.Type())
+		}
+		set.Add(key)
+	}
+	return set
+}
+
+func builtinToArray(args ...Object) Object {
+	if err := expectArgs("to_array", args, SET_OBJ); err != nil {
+		return err
+	}
+	return &Array{Elements: args[0].(*Set).Elements()}
+}
+
+func builtinUnion(args ...Object) Object {
+	return combineSets("union", args, func(inA, inB bool) bool { return inA || inB })
+}
+
+func builtinIntersection(args ...Object) Object {
+	return combineSets("intersection", args, func(inA, inB bool) bool { return inA && inB })
+}
+
+func builtinDifference(args ...Object) Object {
+	return combineSets("difference", args, func(inA, inB bool) bool { return inA && !inB })
+}
+
+// combineSets returns the elements of both sets, first those of a, for which
+// keep holds
+func combineSets(name string, args []Object, keep func(inA, inB bool) bool) Object {
+	if err := expectArgs(name, args, SET_OBJ, SET_OBJ); err != nil {
+		return err
+	}
+	a, b := args[0].(*Set), args[1].(*Set)
+
+	result := NewSet()
+	for _, e := range append(a.Elements(), b.Elements()...) {
+		key := e.(Hashable)
+		if keep(a.Has(key), b.Has(key)) {
+			result.Add(key)
+		}
+	}
+	return result
+}
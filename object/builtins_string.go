@@ -0,0 +1,245 @@
+package object
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// expectArgs checks number and types of the arguments passed to builtin name
+func expectArgs(name string, args []Object, types ...ObjectType) *Error {
+	if len(args) != len(types) {
+		return newError("wrong number of arguments, got=%d, want=%d", len(args), len(types))
+	}
+	for i, t := range types {
+		if args[i].Type() != t {
+			return newError("argument %d to `%s` must be %s, got %s",
+				i+1, name, t, args[i].Type())
+		}
+	}
+	return nil
+}
+
+func builtinSplit(args ...Object) Object {
+	if len(args) == 1 {
+		if err := expectArgs("split", args, STRING_OBJ); err != nil {
+			return err
+		}
+		return stringsToArray(strings.Fields(args[0].(*String).Value))
+	}
+	if err := expectArgs("split", args, STRING_OBJ, STRING_OBJ); err != nil {
+		return err
+	}
+	return stringsToArray(strings.Split(args[0].(*String).Value, args[1].(*String).Value))
+}
+
+func builtinJoin(args ...Object) Object {
+	if err := expectArgs("join", args, ARRAY_OBJ, STRING_OBJ); err != nil {
+		return err
+	}
+
+	elements := args[0].(*Array).Elements
+	parts := make([]string, len(elements))
+	for i, e := range elements {
+		str, ok := e.(*String)
+		if !ok {
+			return newError("elements to `join` must be STRING, got %s", e.Type())
+		}
+		parts[i] = str.Value
+	}
+	return &String{Value: strings.Join(parts, args[1].(*String).Value)}
+}
+
+func builtinTrim(args ...Object) Object {
+	if len(args) == 2 {
+		if err := expectArgs("trim", args, STRING_OBJ, STRING_OBJ); err != nil {
+			return err
+		}
+		return &String{Value: strings.Trim(args[0].(*String).Value, args[1].(*String).Value)}
+	}
+	if err := expectArgs("trim", args, STRING_OBJ); err != nil {
+		return err
+	}
+	return &String{Value: strings.TrimSpace(args[0].(*String).Value)}
+}
+
+func builtinUpper(args ...Object) Object {
+	if err := expectArgs("upper", args, STRING_OBJ); err != nil {
+		return err
+	}
+	return &String{Value: strings.ToUpper(args[0].(*String).Value)}
+}
+
+func builtinLower(args ...Object) Object {
+	if err := expectArgs("lower", args, STRING_OBJ); err != nil {
+		return err
+	}
+	return &String{Value: strings.ToLower(args[0].(*String).Value)}
+}
+
+func builtinContains(args ...Object) Object {
+	if err := expectArgs("contains", args, STRING_OBJ, STRING_OBJ); err != nil {
+		return err
+	}
+	return NativeBoolToBooleanObject(
+		strings.Contains(args[0].(*String).Value, args[1].(*String).Value))
+}
+
+func builtinReplace(args ...Object) Object {
+	if err := expectArgs("replace", args, STRING_OBJ, STRING_OBJ, STRING_OBJ); err != nil {
+		return err
+	}
+	s := args[0].(*String).Value
+	return &String{Value: strings.ReplaceAll(s, args[1].(*String).Value, args[2].(*String).Value)}
+}
+
+// builtinIndexOf returns the index in characters of the first occurrence of
+// substring, or -1 if it's not found
+func builtinIndexOf(args ...Object) Object {
+	if err := expectArgs("index_of", args, STRING_OBJ, STRING_OBJ); err != nil {
+		return err
+	}
+	s := args[0].(*String).Value
+
+	i := strings.Index(s, args[1].(*String).Value)
+	if i < 0 {
+		return &Integer{Value: -1}
+	}
+	return &Integer{Value: int64(utf8.RuneCountInString(s[:i]))}
+}
+
+// builtinSubstr returns length characters starting at character start, or
+// all the rest if length is missing. Both get clamped to the string.
+func builtinSubstr(args ...Object) Object {
+	var err *Error
+	if len(args) == 2 {
+		err = expectArgs("substr", args, STRING_OBJ, INTEGER_OBJ)
+	} else {
+		err = expectArgs("substr", args, STRING_OBJ, INTEGER_OBJ, INTEGER_OBJ)
+	}
+	if err != nil {
+		return err
+	}
+
+	runes := []rune(args[0].(*String).Value)
+	start := clamp(args[1].(*Integer).Value, 0, int64(len(runes)))
+	end := int64(len(runes))
+	if len(args) == 3 {
+		length := args[2].(*Integer).Value
+		if length < 0 {
+			return newError("length to `substr` must not be negative, got %d", length)
+		}
+		end = clamp(start+length, start, end)
+	}
+	return &String{Value: string(runes[start:end])}
+}
+
+// builtinChars splits a string into strings of one character each
+func builtinChars(args ...Object) Object {
+	if err := expectArgs("chars", args, STRING_OBJ); err != nil {
+		return err
+	}
+
+	runes := []rune(args[0].(*String).Value)
+	elements := make([]Object, len(runes))
+	for i, r := range runes {
+		elements[i] = &String{Value: string(r)}
+	}
+	return &Array{Elements: elements}
+}
+
+func builtinStartsWith(args ...Object) Object {
+	if err := expectArgs("starts_with", args, STRING_OBJ, STRING_OBJ); err != nil {
+		return err
+	}
+	return NativeBoolToBooleanObject(
+		strings.HasPrefix(args[0].(*String).Value, args[1].(*String).Value))
+}
+
+func builtinEndsWith(args ...Object) Object {
+	if err := expectArgs("ends_with", args, STRING_OBJ, STRING_OBJ); err != nil {
+		return err
+	}
+	return NativeBoolToBooleanObject(
+		strings.HasSuffix(args[0].(*String).Value, args[1].(*String).Value))
+}
+
+// builtinFormat formats like fmt.Sprintf, monkey values are passed as their
+// native go values, anything else by its Inspect() output
+func builtinFormat(args ...Object) Object {
+	if len(args) == 0 {
+		return newError("wrong number of arguments, got=0, want at least 1")
+	}
+	format, ok := args[0].(*String)
+	if !ok {
+		return newError("argument 1 to `format` must be STRING, got %s", args[0].Type())
+	}
+
+	values := make([]interface{}, len(args)-1)
+	for i, arg := range args[1:] {
+		switch arg := arg.(type) {
+		case *Integer:
+			values[i] = arg.Value
+		case *String:
+			values[i] = arg.Value
+		case *Boolean:
+			values[i] = arg.Value
+		default:
+			values[i] = arg.Inspect()
+		}
+	}
+	return &String{Value: fmt.Sprintf(format.Value, values...)}
+}
+
+func builtinStr(args ...Object) Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments, got=%d, want=1", len(args))
+	}
+	if str, ok := args[0].(*String); ok {
+		return str
+	}
+	return &String{Value: args[0].Inspect()}
+}
+
+func builtinInt(args ...Object) Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments, got=%d, want=1", len(args))
+	}
+
+	switch arg := args[0].(type) {
+	case *Integer:
+		return arg
+	case *Boolean:
+		if arg.Value {
+			return &Integer{Value: 1}
+		}
+		return &Integer{Value: 0}
+	case *String:
+		value, err := strconv.ParseInt(strings.TrimSpace(arg.Value), 10, 64)
+		if err != nil {
+			return newError("could not parse %q as integer", arg.Value)
+		}
+		return &Integer{Value: value}
+	default:
+		return newError("argument to `int` not supported, got %s", arg.Type())
+	}
+}
+
+func stringsToArray(strs []string) *Array {
+	elements := make([]Object, len(strs))
+	for i, s := range strs {
+		elements[i] = &String{Value: s}
+	}
+	return &Array{Elements: elements}
+}
+
+func clamp(value, min, max int64) int64 {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
@@ -1,8 +1,11 @@
 package object
 
+import "sort"
+
 func NewEnvironment() *Environment {
 	s := make(map[string]Object)
-	return &Environment{store: s, outer: nil}
+	c := make(map[string]bool)
+	return &Environment{store: s, constants: c, outer: nil}
 }
 
 func NewEnclosedEnvironment(outer *Environment) *Environment {
@@ -12,8 +15,9 @@ func NewEnclosedEnvironment(outer *Environment) *Environment {
 }
 
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store     map[string]Object
+	constants map[string]bool
+	outer     *Environment
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
@@ -28,3 +32,29 @@ func (e *Environment) Set(name string, val Object) Object {
 	e.store[name] = val
 	return val
 }
+
+func (e *Environment) SetConstant(name string, val Object) Object {
+	e.constants[name] = true
+	return e.Set(name, val)
+}
+
+// Has reports whether name is bound in this environment, ignoring outer ones
+func (e *Environment) Has(name string) bool {
+	_, ok := e.store[name]
+	return ok
+}
+
+// IsConstant reports whether name is bound as constant in this environment
+func (e *Environment) IsConstant(name string) bool {
+	return e.constants[name]
+}
+
+// Names returns the sorted names bound in this environment, ignoring outer ones
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
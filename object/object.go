@@ -2,10 +2,12 @@ package object
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"hash/fnv"
 	"monkey/ast"
 	"monkey/code"
+	"strconv"
 	"strings"
 )
 
@@ -13,6 +15,7 @@ type ObjectType string
 
 const (
 	INTEGER_OBJ           = "INTEGER"
+	FLOAT_OBJ             = "FLOAT"
 	BOOLEAN_OBJ           = "BOOLEAN"
 	NULL_OBJ              = "NULL"
 	STRING_OBJ            = "STRING"
@@ -24,8 +27,26 @@ const (
 	HASH_OBJ              = "HASH"
 	COMPILED_FUNCTION_OBJ = "COMPILED_FUNCTION_OBJ"
 	CLOSURE_OBJ           = "CLOSURE"
+	MODULE_OBJ            = "MODULE"
+	SET_OBJ               = "SET"
+	QUOTE_OBJ             = "QUOTE"
+	MACRO_OBJ             = "MACRO"
 )
 
+// shared by all backends, so that booleans and null can be compared by identity
+var (
+	TRUE  = &Boolean{Value: true}
+	FALSE = &Boolean{Value: false}
+	NULL  = &Null{}
+)
+
+func NativeBoolToBooleanObject(input bool) *Boolean {
+	if input {
+		return TRUE
+	}
+	return FALSE
+}
+
 type Object interface {
 	Type() ObjectType
 	Inspect() string
@@ -33,6 +54,14 @@ type Object interface {
 
 type BuiltinFunction func(arg ...Object) Object
 
+// CallFunction calls back into a monkey function value, a failed call
+// returns an *Error
+type CallFunction func(fn Object, args ...Object) Object
+
+// HigherOrderFunction is a builtin which gets handed a way to call the monkey
+// functions passed to it as arguments
+type HigherOrderFunction func(call CallFunction, args ...Object) Object
+
 // integer object
 type Integer struct {
 	Value int64
@@ -45,6 +74,23 @@ func (i *Integer) Inspect() string {
 	return fmt.Sprintf("%d", i.Value)
 }
 
+// float object
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType {
+	return FLOAT_OBJ
+}
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	// keep floats apart from integers, e.g. 2.0 rather than 2
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
 // boolean object
 type Boolean struct {
 	Value bool
@@ -113,6 +159,8 @@ func (f *Function) Inspect() string {
 
 type Builtin struct {
 	Fn BuiltinFunction
+	// HigherOrderFn is used instead of Fn when set
+	HigherOrderFn HigherOrderFunction
 }
 
 func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
@@ -156,24 +204,116 @@ func (b *Boolean) HashKey() HashKey {
 	return HashKey{Type: b.Type(), Value: value}
 }
 
+func (b *Boolean) KeyEquals(other Hashable) bool {
+	o, ok := other.(*Boolean)
+	return ok && o.Value == b.Value
+}
+
 func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+func (i *Integer) KeyEquals(other Hashable) bool {
+	o, ok := other.(*Integer)
+	return ok && o.Value == i.Value
+}
+
 func (s *String) HashKey() HashKey {
 	h := fnv.New64a()
 	h.Write([]byte(s.Value))
 	return HashKey{Type: s.Type(), Value: h.Sum64()}
 }
 
+func (s *String) KeyEquals(other Hashable) bool {
+	o, ok := other.(*String)
+	return ok && o.Value == s.Value
+}
+
+// HashKey of an array combines the keys of its elements, which must all be
+// hashable, see AsHashable
+func (ao *Array) HashKey() HashKey {
+	h := fnv.New64a()
+	buf := make([]byte, 8)
+	for _, e := range ao.Elements {
+		if key, ok := e.(Hashable); ok {
+			k := key.HashKey()
+			h.Write([]byte(k.Type))
+			binary.LittleEndian.PutUint64(buf, k.Value)
+			h.Write(buf)
+		}
+	}
+	return HashKey{Type: ao.Type(), Value: h.Sum64()}
+}
+
+func (ao *Array) KeyEquals(other Hashable) bool {
+	o, ok := other.(*Array)
+	if !ok || len(o.Elements) != len(ao.Elements) {
+		return false
+	}
+	for i, e := range ao.Elements {
+		key, ok := e.(Hashable)
+		if !ok {
+			return false
+		}
+		otherKey, ok := o.Elements[i].(Hashable)
+		if !ok || !key.KeyEquals(otherKey) {
+			return false
+		}
+	}
+	return true
+}
+
 type HashPair struct {
 	Key   Object
 	Value Object
 }
 
-// FIXME: how to handle hash conflict??
+// Hash keeps its pairs in insertion order. Pairs are bucketed by the HashKey
+// of their keys, keys within a bucket are told apart by KeyEquals.
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	buckets map[HashKey][]int // indexes into pairs
+	pairs   []HashPair
+}
+
+func NewHash() *Hash {
+	return &Hash{buckets: make(map[HashKey][]int)}
+}
+
+// Set adds a pair, or replaces the value of an existing key which keeps its
+// position
+func (h *Hash) Set(key Hashable, value Object) {
+	if i, ok := h.index(key); ok {
+		h.pairs[i].Value = value
+		return
+	}
+	hashKey := key.HashKey()
+	h.buckets[hashKey] = append(h.buckets[hashKey], len(h.pairs))
+	h.pairs = append(h.pairs, HashPair{Key: key, Value: value})
+}
+
+func (h *Hash) Get(key Hashable) (Object, bool) {
+	if i, ok := h.index(key); ok {
+		return h.pairs[i].Value, true
+	}
+	return nil, false
+}
+
+func (h *Hash) index(key Hashable) (int, bool) {
+	for _, i := range h.buckets[key.HashKey()] {
+		if key.KeyEquals(h.pairs[i].Key.(Hashable)) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func (h *Hash) Len() int { return len(h.pairs) }
+
+// Pairs returns the pairs in insertion order
+func (h *Hash) Pairs() []HashPair {
+	pairs := make([]HashPair, len(h.pairs))
+	copy(pairs, h.pairs)
+	return pairs
 }
 
 func (h *Hash) Type() ObjectType { return HASH_OBJ }
@@ -181,7 +321,7 @@ func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for _, pair := range h.Pairs {
+	for _, pair := range h.pairs {
 		pairs = append(pairs, fmt.Sprintf("%s: %s",
 			pair.Key.Inspect(), pair.Value.Inspect()))
 	}
@@ -192,8 +332,29 @@ func (h *Hash) Inspect() string {
 	return out.String()
 }
 
+// Hashable objects can be used as hash keys. Keys with equal HashKey may
+// still differ, KeyEquals tells them apart.
 type Hashable interface {
+	Object
 	HashKey() HashKey
+	KeyEquals(other Hashable) bool
+}
+
+// AsHashable returns obj as hash key. Arrays are only usable as keys if all
+// of their elements are.
+func AsHashable(obj Object) (Hashable, bool) {
+	key, ok := obj.(Hashable)
+	if !ok {
+		return nil, false
+	}
+	if array, ok := obj.(*Array); ok {
+		for _, e := range array.Elements {
+			if _, ok := AsHashable(e); !ok {
+				return nil, false
+			}
+		}
+	}
+	return key, true
 }
 
 type CompiledFunction struct {
@@ -210,9 +371,53 @@ func (cf *CompiledFunction) Inspect() string {
 type Closure struct {
 	Fn   *CompiledFunction
 	Free []Object
+
+	// constant pool and globals of the program the closure was created in,
+	// so that closures exported by a module keep running against its module
+	Constants []Object
+	Globals   []Object
 }
 
 func (c *Closure) Type() ObjectType { return CLOSURE_OBJ }
 func (c *Closure) Inspect() string {
 	return fmt.Sprintf("Closure[%p]", c)
 }
+
+// Module is the value of an import expression, its exports are the top-level
+// bindings of the imported source file
+type Module struct {
+	Name    string
+	Exports *Hash
+}
+
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+func (m *Module) Inspect() string {
+	return fmt.Sprintf("module(%q)", m.Name)
+}
+
+// Quote is an unevaluated piece of source, the value of quote(...)
+type Quote struct {
+	Node ast.Node
+}
+
+func (q *Quote) Type() ObjectType { return QUOTE_OBJ }
+func (q *Quote) Inspect() string {
+	return "QUOTE(" + q.Node.String() + ")"
+}
+
+// Macro is bound by a top-level let of a macro literal, its calls are
+// expanded before the program runs
+type Macro struct {
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+func (m *Macro) Type() ObjectType { return MACRO_OBJ }
+func (m *Macro) Inspect() string {
+	params := []string{}
+	for _, p := range m.Parameters {
+		params = append(params, p.String())
+	}
+	return "macro(" + strings.Join(params, ", ") + ") {\n" + m.Body.String() + "\n}"
+}
@@ -0,0 +1,93 @@
+package object
+
+import (
+	"sync"
+	"testing"
+)
+
+// collidingKey has the same HashKey for all values
+type collidingKey struct {
+	String
+}
+
+func (c *collidingKey) HashKey() HashKey {
+	return HashKey{Type: STRING_OBJ, Value: 42}
+}
+
+func (c *collidingKey) KeyEquals(other Hashable) bool {
+	o, ok := other.(*collidingKey)
+	return ok && o.Value == c.Value
+}
+
+func TestHashKeyCollisions(t *testing.T) {
+	a := &collidingKey{String{Value: "a"}}
+	b := &collidingKey{String{Value: "b"}}
+
+	hash := NewHash()
+	hash.Set(a, &Integer{Value: 1})
+	hash.Set(b, &Integer{Value: 2})
+	hash.Set(&collidingKey{String{Value: "a"}}, &Integer{Value: 3})
+
+	if hash.Len() != 2 {
+		t.Fatalf("hash has wrong number of pairs. want=2, got=%d", hash.Len())
+	}
+	for key, want := range map[*collidingKey]int64{a: 3, b: 2} {
+		value, ok := hash.Get(key)
+		if !ok {
+			t.Fatalf("no pair for key %s", key.Value)
+		}
+		if got := value.(*Integer).Value; got != want {
+			t.Errorf("wrong value for key %s. want=%d, got=%d", key.Value, want, got)
+		}
+	}
+	if _, ok := hash.Get(&collidingKey{String{Value: "c"}}); ok {
+		t.Errorf("found pair for missing key")
+	}
+	if hash.Inspect() != "{a: 3, b: 2}" {
+		t.Errorf("wrong Inspect. got=%q", hash.Inspect())
+	}
+}
+
+func TestArrayHashKey(t *testing.T) {
+	a := &Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "x"}}}
+	b := &Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "x"}}}
+	c := &Array{Elements: []Object{&String{Value: "x"}, &Integer{Value: 1}}}
+
+	if a.HashKey() != b.HashKey() || !a.KeyEquals(b) {
+		t.Errorf("arrays with same elements have different keys")
+	}
+	if a.HashKey() == c.HashKey() || a.KeyEquals(c) {
+		t.Errorf("arrays with different elements have same keys")
+	}
+
+	nested := &Array{Elements: []Object{a, &Boolean{Value: true}}}
+	if _, ok := AsHashable(nested); !ok {
+		t.Errorf("array of hashables is not hashable")
+	}
+	unhashable := &Array{Elements: []Object{a, &Array{Elements: []Object{&Hash{}}}}}
+	if _, ok := AsHashable(unhashable); ok {
+		t.Errorf("array containing a hash is hashable")
+	}
+}
+
+// the random builtins may be called from several VMs at once
+func TestRandomConcurrently(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				SeedRandom(int64(i))
+				if r := builtinRandom().(*Float).Value; r < 0 || r >= 1 {
+					t.Errorf("random() out of range: %v", r)
+				}
+				n := builtinRandInt(&Integer{Value: -5}, &Integer{Value: 5}).(*Integer).Value
+				if n < -5 || n >= 5 {
+					t.Errorf("rand_int(-5, 5) out of range: %d", n)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,95 @@
+package object
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Set holds distinct hashable elements in insertion order
+type Set struct {
+	members *Hash
+}
+
+func NewSet() *Set {
+	return &Set{members: NewHash()}
+}
+
+func (s *Set) Add(e Hashable) { s.members.Set(e, TRUE) }
+
+func (s *Set) Has(e Hashable) bool {
+	_, ok := s.members.Get(e)
+	return ok
+}
+
+func (s *Set) Len() int { return s.members.Len() }
+
+// Elements returns the elements in insertion order
+func (s *Set) Elements() []Object {
+	pairs := s.members.Pairs()
+	elements := make([]Object, len(pairs))
+	for i, pair := range pairs {
+		elements[i] = pair.Key
+	}
+	return elements
+}
+
+func (s *Set) Type() ObjectType { return SET_OBJ }
+func (s *Set) Inspect() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, e := range s.Elements() {
+		elements = append(elements, e.Inspect())
+	}
+
+	out.WriteString("#{")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("}")
+	return out.String()
+}
+
+// Contains implements the `in` operator: membership in sets, keys of hashes,
+// elements of arrays and substrings of strings
+func Contains(collection, value Object) (bool, error) {
+	switch collection := collection.(type) {
+	case *Set:
+		e, ok := AsHashable(value)
+		if !ok {
+			return false, fmt.Errorf("unusable as set element: %s", value.Type())
+		}
+		return collection.Has(e), nil
+	case *Hash:
+		key, ok := AsHashable(value)
+		if !ok {
+			return false, fmt.Errorf("unusable as hash key: %s", value.Type())
+		}
+		_, ok = collection.Get(key)
+		return ok, nil
+	case *Array:
+		for _, e := range collection.Elements {
+			if equalValues(e, value) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case *String:
+		substr, ok := value.(*String)
+		if !ok {
+			return false, fmt.Errorf("type mismatch: %s in STRING", value.Type())
+		}
+		return strings.Contains(collection.Value, substr.Value), nil
+	default:
+		return false, fmt.Errorf("unknown operator: %s in %s", value.Type(), collection.Type())
+	}
+}
+
+// equalValues compares hashable values by value, anything else by identity
+func equalValues(a, b Object) bool {
+	ka, ok := AsHashable(a)
+	if !ok {
+		return a == b
+	}
+	kb, ok := AsHashable(b)
+	return ok && ka.KeyEquals(kb)
+}
@@ -0,0 +1,84 @@
+package object
+
+import "fmt"
+
+// Slice implements left[start:end:step] on arrays and strings, strings are
+// sliced by characters. Bounds are integers or null when missing, negative
+// ones count from the end, and out of range ones get clamped like in Python.
+func Slice(left, start, end, step Object) (Object, error) {
+	var length int64
+	switch left := left.(type) {
+	case *Array:
+		length = int64(len(left.Elements))
+	case *String:
+		length = int64(len([]rune(left.Value)))
+	default:
+		return nil, fmt.Errorf("slice operator not supported: %s", left.Type())
+	}
+
+	indexes, err := sliceIndexes(length, start, end, step)
+	if err != nil {
+		return nil, err
+	}
+
+	switch left := left.(type) {
+	case *Array:
+		elements := make([]Object, len(indexes))
+		for i, index := range indexes {
+			elements[i] = left.Elements[index]
+		}
+		return &Array{Elements: elements}, nil
+	default:
+		runes := []rune(left.(*String).Value)
+		sliced := make([]rune, len(indexes))
+		for i, index := range indexes {
+			sliced[i] = runes[index]
+		}
+		return &String{Value: string(sliced)}, nil
+	}
+}
+
+// sliceIndexes returns the indexes picked by a slice of a sequence of length
+func sliceIndexes(length int64, start, end, step Object) ([]int64, error) {
+	for _, bound := range []Object{start, end, step} {
+		if bound.Type() != INTEGER_OBJ && bound.Type() != NULL_OBJ {
+			return nil, fmt.Errorf("slice indices must be INTEGER, got %s", bound.Type())
+		}
+	}
+
+	by := int64(1)
+	if step, ok := step.(*Integer); ok {
+		by = step.Value
+	}
+	if by == 0 {
+		return nil, fmt.Errorf("slice step must not be zero")
+	}
+
+	// a negative step runs from upper down to, but excluding lower
+	lower, upper := int64(0), length
+	from, to := lower, upper
+	if by < 0 {
+		lower, upper = -1, length-1
+		from, to = upper, lower
+	}
+	if start, ok := start.(*Integer); ok {
+		from = sliceIndex(start.Value, length, lower, upper)
+	}
+	if end, ok := end.(*Integer); ok {
+		to = sliceIndex(end.Value, length, lower, upper)
+	}
+
+	// counted up front, stepping past to may overflow for huge steps
+	indexes := make([]int64, rangeLength(from, to, by))
+	for i := range indexes {
+		indexes[i] = from + int64(i)*by
+	}
+	return indexes, nil
+}
+
+func sliceIndex(index, length, lower, upper int64) int64 {
+	if index < 0 {
+		index += length
+	}
+	return clamp(index, lower, upper)
+}
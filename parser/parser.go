@@ -6,6 +6,7 @@ import (
 	"monkey/lexer"
 	"monkey/token"
 	"strconv"
+	"strings"
 )
 
 type (
@@ -18,7 +19,7 @@ const (
 	_ int = iota
 	LOWEST
 	EQUALS      // ==
-	LESSGREATER // > or <
+	LESSGREATER // > or < or in
 	SUM         // + / -
 	PRODUCT     // * / /
 	PREFIX      // -X or !X
@@ -31,6 +32,7 @@ var precedence = map[token.TokenType]int{
 	token.NOT_EQ:   EQUALS,
 	token.LT:       LESSGREATER,
 	token.GT:       LESSGREATER,
+	token.IN:       LESSGREATER,
 	token.PLUS:     SUM,
 	token.MINUS:    SUM,
 	token.SLASH:    PRODUCT,
@@ -39,11 +41,43 @@ var precedence = map[token.TokenType]int{
 	token.LBRACKET: INDEX,
 }
 
+// ParseError is a syntax error at Pos. When a specific token was expected,
+// Expected is set and Got is the token found instead.
+type ParseError struct {
+	Pos      token.Position
+	Msg      string
+	Expected token.TokenType
+	Got      token.Token
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// ErrorList is the error returned by ParseProgram
+type ErrorList []*ParseError
+
+func (l ErrorList) Error() string {
+	msgs := make([]string, len(l))
+	for i, err := range l {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
 type Parser struct {
 	l         *lexer.Lexer
 	curToken  token.Token
 	peekToken token.Token
-	errors    []string
+	errors    ErrorList
+	comments  []token.Comment
+
+	// panicking is set by an error and cleared once the parser has skipped
+	// to the next statement, errors reported meanwhile are cascades of the
+	// first one and dropped
+	panicking bool
+	panics    int // errors which started a panic, to drop the statements containing them
+	depth     int // braces open at curToken
 
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
@@ -52,13 +86,15 @@ type Parser struct {
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
 		l:      l,
-		errors: []string{},
+		errors: ErrorList{},
 	}
 
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.TEMPLATE_START, p.parseInterpolatedString)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
@@ -66,8 +102,11 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
+	p.registerPrefix(token.SET_LBRACE, p.parseSetLiteral)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.MACRO, p.parseMacroLiteral)
+	p.registerPrefix(token.IMPORT, p.parseImportExpression)
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
@@ -78,6 +117,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.IN, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
 
@@ -103,10 +143,39 @@ func (p *Parser) parseIdentifier() ast.Expression {
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 
-	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	base, digits := integerBase(p.curToken.Literal)
+	value, err := strconv.ParseInt(strings.ReplaceAll(digits, "_", ""), base, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken, "could not parse %q as integer", p.curToken.Literal)
+		return nil
+	}
+
+	lit.Value = value
+	return lit
+}
+
+// integerBase splits an integer literal into its base and digits the way
+// the lexer reads it, a literal without 0x, 0o or 0b prefix is decimal
+func integerBase(literal string) (int, string) {
+	if len(literal) > 2 && literal[0] == '0' {
+		switch literal[1] {
+		case 'x', 'X':
+			return 16, literal[2:]
+		case 'o', 'O':
+			return 8, literal[2:]
+		case 'b', 'B':
+			return 2, literal[2:]
+		}
+	}
+	return 10, literal
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil {
+		p.errorf(p.curToken, "could not parse %q as float", p.curToken.Literal)
 		return nil
 	}
 
@@ -120,13 +189,42 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 	return lit
 }
 
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	exp := &ast.InterpolatedString{
+		Token:   p.curToken,
+		Strings: []string{p.curToken.Literal},
+	}
+
+	for {
+		p.nextToken()
+		e := p.parseExpression(LOWEST)
+		if e == nil {
+			return nil
+		}
+		exp.Expressions = append(exp.Expressions, e)
+
+		if p.peekTokenIs(token.TEMPLATE_MIDDLE) {
+			p.nextToken()
+			exp.Strings = append(exp.Strings, p.curToken.Literal)
+			continue
+		}
+		if !p.expectPeek(token.TEMPLATE_END) {
+			return nil
+		}
+		exp.Strings = append(exp.Strings, p.curToken.Literal)
+		return exp
+	}
+}
+
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	expression := &ast.PrefixExpression{
 		Token:    p.curToken,
 		Operator: p.curToken.Literal,
 	}
 	p.nextToken()
-	expression.Right = p.parseExpression(PREFIX)
+	if expression.Right = p.parseExpression(PREFIX); expression.Right == nil {
+		return nil
+	}
 	return expression
 }
 
@@ -138,23 +236,32 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	}
 	precedence := p.curPrecedence()
 	p.nextToken()
-	expression.Right = p.parseExpression(precedence)
+	if expression.Right = p.parseExpression(precedence); expression.Right == nil {
+		return nil
+	}
 
 	return expression
 }
 
-func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+func (p *Parser) noPrefixParseFnError(tok token.Token) {
+	if tok.Type == token.ILLEGAL {
+		p.panicking = true // the lexer has reported it already
+		p.panics++
+		return
+	}
+	p.errorf(tok, "no prefix parse function for %s found", tok.Type)
 }
 
 func (p *Parser) parseExpression(precedence int) ast.Expression {
 	prefix := p.prefixParseFns[p.curToken.Type]
 	if prefix == nil {
-		p.noPrefixParseFnError(p.curToken.Type)
+		p.noPrefixParseFnError(p.curToken)
 		return nil
 	}
 	leftExp := prefix()
+	if leftExp == nil {
+		return nil
+	}
 
 	for !p.peekTokenIs(token.SEMICOLON) &&
 		precedence < p.peekPrecedence() {
@@ -164,52 +271,107 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 		}
 
 		p.nextToken()
-		leftExp = infix(leftExp)
+		if leftExp = infix(leftExp); leftExp == nil {
+			return nil
+		}
 	}
 
 	return leftExp
 }
 
-func (p *Parser) Errors() []string {
-	return p.errors
+// Errors returns the errors of the lexer followed by those of the parser
+func (p *Parser) Errors() ErrorList {
+	errors := ErrorList{}
+	for _, err := range p.l.Errors() {
+		errors = append(errors, &ParseError{Pos: err.Pos, Msg: err.Msg})
+	}
+	return append(errors, p.errors...)
+}
+
+func (p *Parser) error(err *ParseError) {
+	if p.panicking {
+		return
+	}
+	p.errors = append(p.errors, err)
+	p.panicking = true
+	p.panics++
+}
+
+func (p *Parser) errorf(tok token.Token, format string, a ...interface{}) {
+	p.error(&ParseError{Pos: tok.Pos, Msg: fmt.Sprintf(format, a...), Got: tok})
 }
 
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead",
-		t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.error(&ParseError{
+		Pos: p.peekToken.Pos,
+		Msg: fmt.Sprintf("expected next token to be %s, got %s instead",
+			t, p.peekToken.Type),
+		Expected: t,
+		Got:      p.peekToken,
+	})
 }
 
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+	p.comments = append(p.comments, p.peekToken.Comments...)
+
+	switch p.curToken.Type {
+	case token.LBRACE, token.SET_LBRACE:
+		p.depth++
+	case token.RBRACE:
+		if p.depth > 0 {
+			p.depth--
+		}
+	}
 }
 
-// entry to parse a program
-func (p *Parser) ParseProgram() *ast.Program {
+// synchronize recovers from an error by skipping tokens until the end of the
+// statement in the block at depth: a ';', the token before the '}' closing
+// the block or before a keyword starting a statement. If the closing '}' was
+// consumed by the failed statement, the parser is left on it.
+func (p *Parser) synchronize(depth int) {
+	p.panicking = false
+	for !p.curTokenIs(token.EOF) && p.depth >= depth {
+		if p.depth == depth && (p.curTokenIs(token.SEMICOLON) ||
+			p.peekTokenIs(token.RBRACE) ||
+			p.peekTokenIs(token.LET) ||
+			p.peekTokenIs(token.CONST) ||
+			p.peekTokenIs(token.RETURN)) {
+			return
+		}
+		p.nextToken()
+	}
+}
+
+// entry to parse a program, on errors the returned ErrorList is non-nil and
+// the program holds the statements that parsed fine
+func (p *Parser) ParseProgram() (*ast.Program, error) {
 	program := &ast.Program{}
 	program.Statements = []ast.Statement{}
 
 	for p.curToken.Type != token.EOF {
+		panics := p.panics
 		stmt := p.parseStatement()
-		if stmt != nil {
+		if p.panicking {
+			p.synchronize(0)
+		} else if stmt != nil && p.panics == panics {
+			// statements with errors recovered in their blocks are dropped too
 			program.Statements = append(program.Statements, stmt)
 		}
 		p.nextToken()
 	}
-	if len(p.errors) != 0 {
-		fmt.Printf("parse has %d errors\n", len(p.errors))
-		for i, e := range p.errors {
-			fmt.Println(i, ">", e)
-		}
-		return nil
+	program.Comments = p.comments
+
+	if errors := p.Errors(); len(errors) != 0 {
+		return program, errors
 	}
-	return program
+	return program, nil
 }
 
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
-	case token.LET:
+	case token.LET, token.CONST:
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
@@ -227,12 +389,22 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 
 	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		p.nextToken()
+		if stmt.Type = p.parseType(); stmt.Type == nil {
+			return nil
+		}
+	}
+
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
 
 	p.nextToken() // move over the '='
-	stmt.Value = p.parseExpression(LOWEST)
+	if stmt.Value = p.parseExpression(LOWEST); stmt.Value == nil {
+		return nil
+	}
 
 	if fl, ok := stmt.Value.(*ast.FunctionLiteral); ok {
 		fl.Name = stmt.Name.Value
@@ -280,7 +452,7 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 	p.nextToken() // consume "("
 
 	exp := p.parseExpression(LOWEST)
-	if !p.expectPeek(token.RPAREN) {
+	if exp == nil || !p.expectPeek(token.RPAREN) {
 		return nil
 	}
 	return exp
@@ -289,7 +461,9 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
 
-	array.Elements = p.parseExpressionList(token.RBRACKET)
+	if array.Elements = p.parseExpressionList(token.RBRACKET); array.Elements == nil {
+		return nil
+	}
 	return array
 }
 
@@ -301,14 +475,17 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 	for !p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
 		key := p.parseExpression(LOWEST)
-
-		if !p.expectPeek(token.COLON) {
+		if key == nil || !p.expectPeek(token.COLON) {
 			return nil
 		}
 		p.nextToken() // consume colon
 
 		value := p.parseExpression(LOWEST)
+		if value == nil {
+			return nil
+		}
 		hash.Pairs[key] = value
+		hash.Keys = append(hash.Keys, key)
 
 		// note: here consume/expect the comma if not right brace
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
@@ -322,6 +499,15 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 	return hash
 }
 
+func (p *Parser) parseSetLiteral() ast.Expression {
+	set := &ast.SetLiteral{Token: p.curToken}
+
+	if set.Elements = p.parseExpressionList(token.RBRACE); set.Elements == nil {
+		return nil
+	}
+	return set
+}
+
 func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	list := []ast.Expression{}
 
@@ -331,12 +517,18 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	}
 
 	p.nextToken()
-	list = append(list, p.parseExpression(LOWEST))
+	for {
+		e := p.parseExpression(LOWEST)
+		if e == nil {
+			return nil
+		}
+		list = append(list, e)
 
-	for p.peekTokenIs(token.COMMA) {
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
 		p.nextToken()
 		p.nextToken()
-		list = append(list, p.parseExpression(LOWEST))
 	}
 
 	if !p.expectPeek(end) {
@@ -354,14 +546,15 @@ func (p *Parser) parseIfExpression() ast.Expression {
 
 	p.nextToken()
 	expression.Condition = p.parseExpression(LOWEST)
-
-	if !p.expectPeek(token.RPAREN) {
+	if expression.Condition == nil || !p.expectPeek(token.RPAREN) {
 		return nil
 	}
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
-	expression.Consequence = p.parseBlockStatement()
+	if expression.Consequence = p.parseBlockStatement(); expression.Consequence == nil {
+		return nil
+	}
 	// current token is "}" after parsing block statement
 
 	if p.peekTokenIs(token.ELSE) {
@@ -369,24 +562,40 @@ func (p *Parser) parseIfExpression() ast.Expression {
 		if !p.expectPeek(token.LBRACE) {
 			return nil
 		}
-		expression.Alternative = p.parseBlockStatement()
+		if expression.Alternative = p.parseBlockStatement(); expression.Alternative == nil {
+			return nil
+		}
 	}
 	// at the end, we're on the last token of this "if" expression: '}'
 	return expression
 }
 
+// parseBlockStatement recovers from errors in the statements of the block,
+// it returns nil without parsing anything on an error before it, which the
+// enclosing statement recovers from
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+	if p.panicking {
+		return nil
+	}
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Statement{}
 
+	depth := p.depth
 	p.nextToken()
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
+		panics := p.panics
 		stmt := p.parseStatement()
-		if stmt != nil {
+		if p.panicking {
+			p.synchronize(depth)
+			if p.depth < depth {
+				break // on the closing '}'
+			}
+		} else if stmt != nil && p.panics == panics {
 			block.Statements = append(block.Statements, stmt)
 		}
 		p.nextToken()
 	}
+	block.Rbrace = p.curToken.Pos
 	return block
 }
 
@@ -397,41 +606,165 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
-	fl.Parameters = p.parseFunctionParameters()
+	fl.Parameters, fl.ParameterTypes = p.parseFunctionParameters()
+	if fl.Parameters == nil {
+		return nil
+	}
+	if p.peekTokenIs(token.ARROW) {
+		p.nextToken()
+		p.nextToken()
+		if fl.ReturnType = p.parseType(); fl.ReturnType == nil {
+			return nil
+		}
+	}
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
-	fl.Body = p.parseBlockStatement()
+	if fl.Body = p.parseBlockStatement(); fl.Body == nil {
+		return nil
+	}
 	return fl
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
+func (p *Parser) parseMacroLiteral() ast.Expression {
+	ml := &ast.MacroLiteral{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	params, types := p.parseFunctionParameters()
+	if params == nil {
+		return nil
+	}
+	if types != nil {
+		p.errorf(ml.Token, "macro parameters cannot have types")
+		return nil
+	}
+	ml.Parameters = params
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	if ml.Body = p.parseBlockStatement(); ml.Body == nil {
+		return nil
+	}
+	return ml
+}
+
+// parseFunctionParameters returns the parameters and, if any of them is
+// annotated, the types of all of them
+func (p *Parser) parseFunctionParameters() ([]*ast.Identifier, []ast.TypeExpression) {
 	identifiers := []*ast.Identifier{}
+	types := []ast.TypeExpression{}
+	annotated := false
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return identifiers
+		return identifiers, nil
 	}
-	p.nextToken()
-
-	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	identifiers = append(identifiers, ident)
 
-	for p.peekTokenIs(token.COMMA) {
+	for {
 		p.nextToken()
-		p.nextToken()
-		ident = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 		identifiers = append(identifiers, ident)
+
+		var t ast.TypeExpression
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken()
+			p.nextToken()
+			if t = p.parseType(); t == nil {
+				return nil, nil
+			}
+			annotated = true
+		}
+		types = append(types, t)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
 	}
 
 	if !p.expectPeek(token.RPAREN) {
-		return nil
+		return nil, nil
+	}
+	if !annotated {
+		types = nil
+	}
+	return identifiers, types
+}
+
+// parseType parses the type annotation starting at the current token, e.g.
+// int, [string], {string: int}, #{int} or fn(int) -> bool
+func (p *Parser) parseType() ast.TypeExpression {
+	switch p.curToken.Type {
+	case token.IDENT:
+		return &ast.NamedType{Token: p.curToken, Name: p.curToken.Literal}
+
+	case token.LBRACKET:
+		t := &ast.ArrayType{Token: p.curToken}
+		p.nextToken()
+		if t.Element = p.parseType(); t.Element == nil || !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+		return t
+
+	case token.SET_LBRACE:
+		t := &ast.SetType{Token: p.curToken}
+		p.nextToken()
+		if t.Element = p.parseType(); t.Element == nil || !p.expectPeek(token.RBRACE) {
+			return nil
+		}
+		return t
+
+	case token.LBRACE:
+		t := &ast.HashType{Token: p.curToken}
+		p.nextToken()
+		if t.Key = p.parseType(); t.Key == nil || !p.expectPeek(token.COLON) {
+			return nil
+		}
+		p.nextToken()
+		if t.Value = p.parseType(); t.Value == nil || !p.expectPeek(token.RBRACE) {
+			return nil
+		}
+		return t
+
+	case token.FUNCTION:
+		t := &ast.FunctionType{Token: p.curToken, Parameters: []ast.TypeExpression{}}
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		for !p.peekTokenIs(token.RPAREN) {
+			p.nextToken()
+			param := p.parseType()
+			if param == nil {
+				return nil
+			}
+			t.Parameters = append(t.Parameters, param)
+			if !p.peekTokenIs(token.COMMA) {
+				break
+			}
+			p.nextToken()
+		}
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+		if p.peekTokenIs(token.ARROW) {
+			p.nextToken()
+			p.nextToken()
+			if t.Return = p.parseType(); t.Return == nil {
+				return nil
+			}
+		}
+		return t
 	}
-	return identifiers
+
+	p.errorf(p.curToken, "expected type, got %s instead", p.curToken.Type)
+	return nil
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
-	exp.Arguments = p.parseCallArguments()
+	if exp.Arguments = p.parseCallArguments(); exp.Arguments == nil {
+		return nil
+	}
 	return exp
 }
 
@@ -440,15 +773,65 @@ func (p *Parser) parseCallArguments() []ast.Expression {
 }
 
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
-	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+	tok := p.curToken
+	if p.peekTokenIs(token.COLON) {
+		return p.parseSliceExpression(tok, left, nil)
+	}
+
 	p.nextToken() // consume the '['
-	exp.Index = p.parseExpression(LOWEST)
+	index := p.parseExpression(LOWEST)
+	if index == nil {
+		return nil
+	}
+	if p.peekTokenIs(token.COLON) {
+		return p.parseSliceExpression(tok, left, index)
+	}
+
+	exp := &ast.IndexExpression{Token: tok, Left: left, Index: index}
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	return exp
+}
+
+// parseSliceExpression parses the rest of a slice after its start, the peek
+// token is the first ':'
+func (p *Parser) parseSliceExpression(tok token.Token, left, start ast.Expression) ast.Expression {
+	exp := &ast.SliceExpression{Token: tok, Left: left, Start: start}
+	p.nextToken() // consume the ':'
+
+	if !p.peekTokenIs(token.COLON) && !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		if exp.End = p.parseExpression(LOWEST); exp.End == nil {
+			return nil
+		}
+	}
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		if !p.peekTokenIs(token.RBRACKET) {
+			p.nextToken()
+			if exp.Step = p.parseExpression(LOWEST); exp.Step == nil {
+				return nil
+			}
+		}
+	}
+
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
 	}
 	return exp
 }
 
+func (p *Parser) parseImportExpression() ast.Expression {
+	exp := &ast.ImportExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.STRING) {
+		return nil
+	}
+	exp.Path = p.parseStringLiteral()
+	return exp
+}
+
 func (p *Parser) curTokenIs(t token.TokenType) bool {
 	return p.curToken.Type == t
 }
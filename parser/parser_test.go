@@ -3,6 +3,7 @@ package parser
 import (
 	"monkey/ast"
 	"monkey/lexer"
+	"monkey/token"
 	"testing"
 )
 
@@ -15,8 +16,8 @@ func TestLetStatements(t *testing.T) {
 	l := lexer.New(input)
 	p := New(l)
 
-	program := p.ParseProgram()
-	checkParserErrors(t, p)
+	program, err := p.ParseProgram()
+	checkParserErrors(t, err)
 
 	if program == nil {
 		t.Fatalf("ParseProgram() returned nil")
@@ -40,6 +41,44 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestConstStatements(t *testing.T) {
+	input := `
+	const x = 5;
+	let y = x;
+	`
+	l := lexer.New(input)
+	p := New(l)
+
+	program, err := p.ParseProgram()
+	checkParserErrors(t, err)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements doesn't contain 2 statements. got=%d",
+			len(program.Statements))
+	}
+
+	tests := []struct {
+		expectedIdentifier string
+		expectedConst      bool
+	}{
+		{"x", true},
+		{"y", false},
+	}
+
+	for i, tt := range tests {
+		letStmt, ok := program.Statements[i].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("stmt not *ast.LetStatement. got=%T", program.Statements[i])
+		}
+		if letStmt.Name.Value != tt.expectedIdentifier {
+			t.Errorf("letStmt.Name.Value not '%s'. got=%s", tt.expectedIdentifier, letStmt.Name.Value)
+		}
+		if letStmt.IsConst() != tt.expectedConst {
+			t.Errorf("letStmt.IsConst() wrong. want=%t, got=%t", tt.expectedConst, letStmt.IsConst())
+		}
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	input := `
 	return 5;
@@ -49,8 +88,8 @@ func TestReturnStatements(t *testing.T) {
 	l := lexer.New(input)
 	p := New(l)
 
-	program := p.ParseProgram()
-	checkParserErrors(t, p)
+	program, err := p.ParseProgram()
+	checkParserErrors(t, err)
 
 	if len(program.Statements) != 3 {
 		t.Fatalf("program.Statements doesn't contain 3 statements. got=%d",
@@ -70,11 +109,14 @@ func TestReturnStatements(t *testing.T) {
 	}
 }
 
-func checkParserErrors(t *testing.T, p *Parser) {
-	errors := p.Errors()
-	if len(errors) == 0 {
+func checkParserErrors(t *testing.T, err error) {
+	if err == nil {
 		return
 	}
+	errors, ok := err.(ErrorList)
+	if !ok {
+		t.Fatalf("err is not ErrorList. got=%T", err)
+	}
 
 	t.Errorf("parse has %d errors", len(errors))
 	for _, msg := range errors {
@@ -161,13 +203,25 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"3 + 4 * 5 == 3 * 1 + 4 * 5",
 			"((3 + (4 * 5)) == ((3 * 1) + (4 * 5)))",
 		},
+		{
+			`{"b": 1 + 2, "a": 3, 1: -4}`,
+			"{b:(1 + 2), a:3, 1:(-4)}",
+		},
+		{
+			"a + 1 in #{1, b * 2} == true",
+			"(((a + 1) in #{1, (b * 2)}) == true)",
+		},
+		{
+			"x in y < z",
+			"((x in y) < z)",
+		},
 	}
 
 	for _, tt := range tests {
 		l := lexer.New(tt.input)
 		p := New(l)
-		program := p.ParseProgram()
-		checkParserErrors(t, p)
+		program, err := p.ParseProgram()
+		checkParserErrors(t, err)
 
 		actual := program.String()
 		if actual != tt.expected {
@@ -175,3 +229,412 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 		}
 	}
 }
+
+func TestImportExpression(t *testing.T) {
+	input := `import "lib/math"`
+
+	l := lexer.New(input)
+	p := New(l)
+	program, err := p.ParseProgram()
+	checkParserErrors(t, err)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+	exp, ok := stmt.Expression.(*ast.ImportExpression)
+	if !ok {
+		t.Fatalf("exp is not ast.ImportExpression. got=%T", stmt.Expression)
+	}
+	path, ok := exp.Path.(*ast.StringLiteral)
+	if !ok || path.Value != "lib/math" {
+		t.Errorf("exp.Path is not \"lib/math\". got=%T (%+v)", exp.Path, exp.Path)
+	}
+	if exp.String() != input {
+		t.Errorf("exp.String() wrong. want=%q, got=%q", input, exp.String())
+	}
+}
+
+func TestSetLiteral(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"#{}", []string{}},
+		{"#{1, (2 + 3), a}", []string{"1", "(2 + 3)", "a"}},
+		{"#{#{1}}", []string{"#{1}"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program, err := p.ParseProgram()
+		checkParserErrors(t, err)
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+				program.Statements[0])
+		}
+		set, ok := stmt.Expression.(*ast.SetLiteral)
+		if !ok {
+			t.Fatalf("exp is not ast.SetLiteral. got=%T", stmt.Expression)
+		}
+		if len(set.Elements) != len(tt.expected) {
+			t.Fatalf("len(set.Elements) wrong. want=%d, got=%d",
+				len(tt.expected), len(set.Elements))
+		}
+		for i, e := range set.Elements {
+			if e.String() != tt.expected[i] {
+				t.Errorf("set.Elements[%d] wrong. want=%q, got=%q", i, tt.expected[i], e.String())
+			}
+		}
+		if set.String() != tt.input {
+			t.Errorf("set.String() wrong. want=%q, got=%q", tt.input, set.String())
+		}
+	}
+}
+
+func TestMacroLiteralParsing(t *testing.T) {
+	input := `macro(x, y) { x + y; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program, err := p.ParseProgram()
+	checkParserErrors(t, err)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements wrong. got=%d", len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+	macro, ok := stmt.Expression.(*ast.MacroLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.MacroLiteral. got=%T", stmt.Expression)
+	}
+	if len(macro.Parameters) != 2 || macro.Parameters[0].Value != "x" || macro.Parameters[1].Value != "y" {
+		t.Fatalf("macro.Parameters wrong. got=%v", macro.Parameters)
+	}
+	if len(macro.Body.Statements) != 1 || macro.Body.Statements[0].String() != "(x + y)" {
+		t.Fatalf("macro.Body wrong. got=%q", macro.Body.String())
+	}
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x: int = 1", "let x: int = 1;"},
+		{"const xs: [[string]] = []", "const xs: [[string]] = [];"},
+		{"let h: {string: #{int}} = {}", "let h: {string: #{int}} = {};"},
+		{"let f: fn(int, [int]) -> bool = g", "let f: fn(int, [int]) -> bool = g;"},
+		{"let f: fn() = g", "let f: fn() = g;"},
+		{"fn(a: string, b: [int]) -> bool { true }", "fn (a: string,b: [int]) -> bool{true}"},
+		{"fn(a, b: int) { a }", "fn (a,b: int){a}"},
+		{"fn(f: fn(int) -> int) -> {string: int} { {} }", "fn (f: fn(int) -> int) -> {string: int}{{}}"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program, err := p.ParseProgram()
+		checkParserErrors(t, err)
+
+		if program.String() != tt.expected {
+			t.Errorf("%q: wrong program. want=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+
+	p := New(lexer.New("fn(a, b: int) { a }"))
+	program, _ := p.ParseProgram()
+	fl := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if len(fl.ParameterTypes) != 2 || fl.ParameterTypes[0] != nil || fl.ParameterTypes[1].String() != "int" {
+		t.Errorf("fl.ParameterTypes wrong. got=%v", fl.ParameterTypes)
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x: 1 = 1", "1:8: expected type, got INT instead"},
+		{"let x: [int = 1", "1:13: expected next token to be ], got = instead"},
+		{"fn(a: {string}) { a }", "1:14: expected next token to be :, got } instead"},
+		{"macro(a: int) { a }", "1:1: macro parameters cannot have types"},
+	}
+	for _, tt := range errorTests {
+		p := New(lexer.New(tt.input))
+		_, err := p.ParseProgram()
+		errors, ok := err.(ErrorList)
+		if !ok || len(errors) != 1 || errors[0].Error() != tt.expected {
+			t.Errorf("%q: wrong errors. want=%q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestSliceExpression(t *testing.T) {
+	tests := []struct {
+		input string
+		start string
+		end   string
+		step  string
+	}{
+		{"a[1:2]", "1", "2", ""},
+		{"a[:2]", "", "2", ""},
+		{"a[1:]", "1", "", ""},
+		{"a[:]", "", "", ""},
+		{"a[::]", "", "", ""},
+		{"a[::-1]", "", "", "(-1)"},
+		{"a[x + 1:-1:2 * y]", "(x + 1)", "(-1)", "(2 * y)"},
+	}
+
+	str := func(e ast.Expression) string {
+		if e == nil {
+			return ""
+		}
+		return e.String()
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program, err := p.ParseProgram()
+		checkParserErrors(t, err)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := stmt.Expression.(*ast.SliceExpression)
+		if !ok {
+			t.Fatalf("exp is not ast.SliceExpression. got=%T", stmt.Expression)
+		}
+		if str(exp.Left) != "a" {
+			t.Errorf("exp.Left wrong. got=%q", str(exp.Left))
+		}
+		if str(exp.Start) != tt.start || str(exp.End) != tt.end || str(exp.Step) != tt.step {
+			t.Errorf("%s: bounds wrong. want=[%s:%s:%s], got=[%s:%s:%s]", tt.input,
+				tt.start, tt.end, tt.step, str(exp.Start), str(exp.End), str(exp.Step))
+		}
+	}
+}
+
+func TestLexerErrors(t *testing.T) {
+	input := `let = 1; let a = "x\q"; "abc`
+
+	l := lexer.New(input)
+	p := New(l)
+	program, err := p.ParseProgram()
+	if program.String() != "let a = x;abc" {
+		t.Fatalf("wrong partial program. got=%q", program.String())
+	}
+
+	expected := []string{
+		`1:20: unknown escape sequence \q`,
+		"1:25: unterminated string",
+		"1:5: expected next token to be IDENT, got = instead",
+	}
+	errors, ok := err.(ErrorList)
+	if !ok || len(errors) != len(expected) {
+		t.Fatalf("wrong errors. want=%q, got=%q", expected, err)
+	}
+	for i, err := range errors {
+		if err.Error() != expected[i] {
+			t.Errorf("errors[%d] wrong. want=%q, got=%q", i, expected[i], err)
+		}
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input    string
+		program  string
+		expected []string
+	}{
+		{
+			"let x = f(1, 2; let y = 2; y",
+			"let y = 2;y",
+			[]string{"1:15: expected next token to be ), got ; instead"},
+		},
+		{
+			"let = 1; let b = ; let c = 3",
+			"let c = 3;",
+			[]string{
+				"1:5: expected next token to be IDENT, got = instead",
+				"1:18: no prefix parse function for ; found",
+			},
+		},
+		{
+			"if (x { 1 }; 2",
+			"2",
+			[]string{"1:7: expected next token to be ), got { instead"},
+		},
+		{
+			"let a = [1, 2 3]\nlet b = 4",
+			"let b = 4;",
+			[]string{"1:15: expected next token to be ], got INT instead"},
+		},
+		{
+			"let f = fn(a) { a + ; a * 2 }; f(1)",
+			"f(1)",
+			[]string{"1:21: no prefix parse function for ; found"},
+		},
+		{
+			"fn() { if (x) { 1 } 2 + }; 3",
+			"3",
+			[]string{"1:25: no prefix parse function for } found"},
+		},
+		{
+			"let x = 1 } let y = 2",
+			"let x = 1;let y = 2;",
+			[]string{"1:11: no prefix parse function for } found"},
+		},
+		{
+			"let f = fn(a { a }; let ok = 1;",
+			"let ok = 1;",
+			[]string{"1:14: expected next token to be ), got { instead"},
+		},
+		{
+			"puts(fn(x { x }); let k = 2;",
+			"let k = 2;",
+			[]string{"1:11: expected next token to be ), got { instead"},
+		},
+		{
+			"let f = fn(a { a } + ); let ok = 1;",
+			"let ok = 1;",
+			[]string{"1:14: expected next token to be ), got { instead"},
+		},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program, err := p.ParseProgram()
+
+		if program.String() != tt.program {
+			t.Errorf("%q: wrong partial program. want=%q, got=%q",
+				tt.input, tt.program, program.String())
+		}
+		errors, ok := err.(ErrorList)
+		if !ok || len(errors) != len(tt.expected) {
+			t.Errorf("%q: wrong errors. want=%q, got=%q", tt.input, tt.expected, err)
+			continue
+		}
+		for i, err := range errors {
+			if err.Error() != tt.expected[i] {
+				t.Errorf("%q: errors[%d] wrong. want=%q, got=%q",
+					tt.input, i, tt.expected[i], err)
+			}
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	p := New(lexer.New("let x = 1;\nlet 5 = x;"))
+	_, err := p.ParseProgram()
+
+	errors, ok := err.(ErrorList)
+	if !ok || len(errors) != 1 {
+		t.Fatalf("wrong errors. got=%q", err)
+	}
+	pe := errors[0]
+	if pe.Pos != (token.Position{Line: 2, Column: 5}) {
+		t.Errorf("pe.Pos wrong. got=%s", pe.Pos)
+	}
+	if pe.Expected != token.IDENT {
+		t.Errorf("pe.Expected wrong. got=%q", pe.Expected)
+	}
+	if pe.Got.Type != token.INT || pe.Got.Literal != "5" {
+		t.Errorf("pe.Got wrong. got=%+v", pe.Got)
+	}
+	if pe.Msg != "expected next token to be IDENT, got INT instead" {
+		t.Errorf("pe.Msg wrong. got=%q", pe.Msg)
+	}
+}
+
+func TestInterpolatedString(t *testing.T) {
+	tests := []struct {
+		input       string
+		strings     []string
+		expressions []string
+	}{
+		{`"a${x}b"`, []string{"a", "b"}, []string{"x"}},
+		{`"${(1 + 2)}${f(y)}"`, []string{"", "", ""}, []string{"(1 + 2)", "f(y)"}},
+		{`"${"in${n}"}!"`, []string{"", "!"}, []string{`"in${n}"`}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program, err := p.ParseProgram()
+		checkParserErrors(t, err)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := stmt.Expression.(*ast.InterpolatedString)
+		if !ok {
+			t.Fatalf("exp is not ast.InterpolatedString. got=%T", stmt.Expression)
+		}
+		if len(exp.Strings) != len(tt.strings) || len(exp.Expressions) != len(tt.expressions) {
+			t.Fatalf("%s: wrong number of parts. got=%q, %d expressions",
+				tt.input, exp.Strings, len(exp.Expressions))
+		}
+		for i, s := range exp.Strings {
+			if s != tt.strings[i] {
+				t.Errorf("%s: exp.Strings[%d] wrong. want=%q, got=%q", tt.input, i, tt.strings[i], s)
+			}
+		}
+		for i, e := range exp.Expressions {
+			if e.String() != tt.expressions[i] {
+				t.Errorf("%s: exp.Expressions[%d] wrong. want=%q, got=%q",
+					tt.input, i, tt.expressions[i], e.String())
+			}
+		}
+		if exp.String() != tt.input {
+			t.Errorf("exp.String() wrong. want=%q, got=%q", tt.input, exp.String())
+		}
+	}
+}
+
+func TestProgramComments(t *testing.T) {
+	input := `# setup
+let x = 5; // five
+let y = fn(a /* unused */) { a }; /* done */`
+
+	l := lexer.New(input)
+	p := New(l)
+	program, err := p.ParseProgram()
+	checkParserErrors(t, err)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements wrong. got=%d", len(program.Statements))
+	}
+	expected := []string{"# setup", "// five", "/* unused */", "/* done */"}
+	if len(program.Comments) != len(expected) {
+		t.Fatalf("program.Comments wrong. want=%q, got=%+v", expected, program.Comments)
+	}
+	for i, c := range program.Comments {
+		if c.Text != expected[i] {
+			t.Errorf("program.Comments[%d] wrong. want=%q, got=%q", i, expected[i], c.Text)
+		}
+	}
+}
+
+func TestMalformedNumber(t *testing.T) {
+	l := lexer.New("let x = 0x; let y = 1 + 2 → 3; let z = 0755;")
+	p := New(l)
+	_, err := p.ParseProgram()
+
+	expected := []string{
+		`1:9: invalid number "0x": missing digits after 0x`,
+		`1:27: illegal character '→'`,
+		`1:40: invalid number "0755": leading zero in decimal literal, use 0o for octal`,
+	}
+	errors, ok := err.(ErrorList)
+	if !ok || len(errors) != len(expected) {
+		t.Fatalf("wrong errors. want=%q, got=%q", expected, err)
+	}
+	for i, err := range errors {
+		if err.Error() != expected[i] {
+			t.Errorf("errors[%d] wrong. want=%q, got=%q", i, expected[i], err)
+		}
+	}
+}
@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"io"
 	"monkey/compiler"
+	"monkey/evaluator"
 	"monkey/lexer"
+	"monkey/module"
 	"monkey/object"
 	"monkey/parser"
+	"monkey/types"
 	"monkey/vm"
 )
 
@@ -25,6 +28,12 @@ func Start(in io.Reader, out io.Writer) {
 	for i, v := range object.Builtins {
 		symbolTable.DefineBuiltin(i, v.Name)
 	}
+	// types of globals are kept like their symbols
+	checker := types.NewChecker()
+	// macros defined on one line can be used on later ones
+	macroEnv := object.NewEnvironment()
+	// modules stay loaded across lines
+	loader := vm.NewModuleLoader(module.DefaultSearchPath()...)
 
 	for {
 		fmt.Print(PROMPT)
@@ -36,20 +45,44 @@ func Start(in io.Reader, out io.Writer) {
 		line := scanner.Text()
 		l := lexer.New(line)
 		p := parser.New(l)
-		program := p.ParseProgram()
-		if program == nil {
+		program, err := p.ParseProgram()
+		if err != nil {
+			printParserErrors(out, p.Errors())
 			continue
 		}
 
-		comp := compiler.NewWithState(symbolTable, constants)
-		err := comp.Compile(program)
+		evaluator.DefineMacros(program, macroEnv)
+		err = evaluator.ExpandMacros(program, macroEnv)
 		if err != nil {
+			fmt.Fprintf(out, "Woops! Macro expansion failed:\n %s\n", err)
+			continue
+		}
+		if len(program.Statements) == 0 {
+			continue // e.g. only macro definitions
+		}
+
+		err = checker.Check(program)
+		if errors, ok := err.(types.ErrorList); ok {
+			printTypeErrors(out, errors)
+			continue
+		} else if err != nil {
+			fmt.Fprintf(out, "Woops! Type check failed:\n %s\n", err)
+			continue
+		}
+
+		comp := compiler.NewWithState(symbolTable, constants)
+		err = comp.Compile(program)
+		if errors, ok := err.(compiler.ErrorList); ok {
+			printCompilerErrors(out, errors)
+			continue
+		} else if err != nil {
 			fmt.Fprintf(out, "Woops! Compilation failed:\n %s\n", err)
 			continue
 		}
 		code := comp.Bytecode()
 		constants = code.Constants
 		machine := vm.NewWithGlobalsStore(code, globals)
+		machine.SetModuleLoader(loader)
 		err = machine.Run()
 		if err != nil {
 			fmt.Fprintf(out, "Woops! Executing bytecode failed:\n %s\n", err)
@@ -60,3 +93,24 @@ func Start(in io.Reader, out io.Writer) {
 		io.WriteString(out, "\n")
 	}
 }
+
+func printParserErrors(out io.Writer, errors parser.ErrorList) {
+	io.WriteString(out, "Woops! Parsing failed:\n")
+	for _, err := range errors {
+		io.WriteString(out, " "+err.Error()+"\n")
+	}
+}
+
+func printTypeErrors(out io.Writer, errors types.ErrorList) {
+	io.WriteString(out, "Woops! Type check failed:\n")
+	for _, err := range errors {
+		io.WriteString(out, " "+err.Error()+"\n")
+	}
+}
+
+func printCompilerErrors(out io.Writer, errors compiler.ErrorList) {
+	io.WriteString(out, "Woops! Compilation failed:\n")
+	for _, err := range errors {
+		io.WriteString(out, " "+err.Error()+"\n")
+	}
+}
@@ -0,0 +1,37 @@
+package repl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStart(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = 1\na + 2", "1\n3\n"},
+		{
+			// a macro defined on one line expands on later ones
+			"let unless = macro(c, a, b) { quote(if (!(unquote(c))) { unquote(a) } else { unquote(b) }) };\n" +
+				"unless(10 > 5, 1, 2)\nunless(1 > 5, 3, 4)",
+			"2\n3\n",
+		},
+		{
+			"let twice = macro(x) { quote(unquote(x) + unquote(x)) };\nlet n = twice(2);\nn",
+			"4\n4\n",
+		},
+		{
+			"let m = macro(x) { 1 };\nm(1, 2)",
+			"Woops! Macro expansion failed:\n 1:1: macro m: wrong number of arguments: want=1, got=2\n",
+		},
+	}
+
+	for _, tt := range tests {
+		var out strings.Builder
+		Start(strings.NewReader(tt.input), &out)
+		if out.String() != tt.expected {
+			t.Errorf("%q: wrong output. want=%q, got=%q", tt.input, tt.expected, out.String())
+		}
+	}
+}
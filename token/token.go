@@ -1,10 +1,32 @@
 package token
 
+import "fmt"
+
 type TokenType string
 
 type Token struct {
-	Type    TokenType
-	Literal string
+	Type     TokenType
+	Literal  string
+	Pos      Position  // where the token starts
+	Comments []Comment // the comments right before the token
+}
+
+// Position in the source, Line and Column start at 1, columns count
+// characters
+type Position struct {
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Comment is kept as trivia of the token following it
+type Comment struct {
+	Text    string // including delimiters, e.g. "// note" or "/* note */"
+	Pos     Position
+	NewLine bool // whether a line break separates it from what precedes it
 }
 
 const (
@@ -13,9 +35,15 @@ const (
 
 	// Identifiers + literals
 	IDENT  = "IDENT" // add, foobar, x, y, ...
-	INT    = "INT"   // 123456
+	INT    = "INT"   // 123456, 0x1f, 1_000
+	FLOAT  = "FLOAT" // 1.5, 1e-3
 	STRING = "STRING"
 
+	// Parts of a string with interpolations: "start${a}middle${b}end"
+	TEMPLATE_START  = "TEMPLATE_START"
+	TEMPLATE_MIDDLE = "TEMPLATE_MIDDLE"
+	TEMPLATE_END    = "TEMPLATE_END"
+
 	// Operators
 	ASSIGN   = "="
 	PLUS     = "+"
@@ -33,6 +61,7 @@ const (
 	COMMA     = ","
 	SEMICOLON = ";"
 	COLON     = ":"
+	ARROW     = "->" // before the result type of a function
 
 	LPAREN   = "("
 	RPAREN   = ")"
@@ -41,24 +70,34 @@ const (
 	LBRACKET = "["
 	RBRACKET = "]"
 
+	SET_LBRACE = "#{"
+
 	// Keywords
 	FUNCTION = "FUNCTION"
 	LET      = "LET"
+	CONST    = "CONST"
 	TRUE     = "TRUE"
 	FALSE    = "FALSE"
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	IMPORT   = "IMPORT"
+	IN       = "IN"
+	MACRO    = "MACRO"
 )
 
 var keywords = map[string]TokenType{
 	"fn":     FUNCTION,
 	"let":    LET,
+	"const":  CONST,
 	"true":   TRUE,
 	"false":  FALSE,
 	"if":     IF,
 	"else":   ELSE,
 	"return": RETURN,
+	"import": IMPORT,
+	"in":     IN,
+	"macro":  MACRO,
 }
 
 func LookupIdent(ident string) TokenType {
@@ -0,0 +1,624 @@
+package types
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/object"
+	"monkey/token"
+	"strings"
+)
+
+// Error is a type error at Pos
+type Error struct {
+	Pos token.Position
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// ErrorList is the error returned by Check
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	msgs := make([]string, len(l))
+	for i, err := range l {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Checker checks programs one after the other, keeping the types of their
+// globals like the compiler keeps its symbol table, e.g. for the REPL
+type Checker struct {
+	globals *scope
+}
+
+func NewChecker() *Checker {
+	globals := newScope(nil)
+	for _, v := range object.Builtins {
+		globals.names[v.Name] = Any
+	}
+	return &Checker{globals: globals}
+}
+
+// Check checks program with a new Checker
+func Check(program *ast.Program) error {
+	return NewChecker().Check(program)
+}
+
+// Check checks the types of program. It goes on after an error, so that all
+// of them are found, and returns them as an ErrorList.
+func (c *Checker) Check(program *ast.Program) error {
+	ch := &checker{
+		scope:       c.globals,
+		annotations: map[ast.TypeExpression]Type{},
+		types:       map[ast.Expression]Type{},
+	}
+	for _, s := range program.Statements {
+		ch.statement(s)
+	}
+	if len(ch.errors) > 0 {
+		return ch.errors
+	}
+	return nil
+}
+
+// scope holds the types of the names bound in a function, or at the top
+// level. Like in the compiler, blocks don't have scopes of their own.
+type scope struct {
+	names map[string]Type
+	outer *scope
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{names: map[string]Type{}, outer: outer}
+}
+
+// lookup returns the type of name, any if it's undefined, which is left to
+// the compiler to report
+func (s *scope) lookup(name string) Type {
+	for ; s != nil; s = s.outer {
+		if t, ok := s.names[name]; ok {
+			return t
+		}
+	}
+	return Any
+}
+
+// function is the function whose body is being checked
+type function struct {
+	result  Type // the annotated return type, nil without annotation
+	returns Type // the join of the types returned so far
+}
+
+type checker struct {
+	scope       *scope
+	function    *function
+	annotations map[ast.TypeExpression]Type // resolved once, to report once
+	types       map[ast.Expression]Type     // of the expressions checked
+	errors      ErrorList
+}
+
+func (c *checker) errorf(pos token.Position, format string, a ...interface{}) {
+	c.errors = append(c.errors, &Error{Pos: pos, Msg: fmt.Sprintf(format, a...)})
+}
+
+func (c *checker) statement(s ast.Statement) {
+	switch s := s.(type) {
+	case *ast.LetStatement:
+		c.let(s)
+	case *ast.ReturnStatement:
+		t := c.expression(s.ReturnValue)
+		if c.function != nil {
+			c.checkReturn(s.ReturnValue, t)
+		}
+	case *ast.ExpressionStatement:
+		c.expression(s.Expression)
+	case *ast.BlockStatement:
+		c.block(s)
+	}
+}
+
+func (c *checker) let(s *ast.LetStatement) {
+	name := s.Name.Value
+	declared := c.annotation(s.Type)
+
+	// like in the compiler the name is bound before its value, so that
+	// functions can call themselves
+	switch {
+	case declared != nil:
+		c.scope.names[name] = declared
+	case isFunctionLiteral(s.Value):
+		c.scope.names[name] = c.signature(s.Value.(*ast.FunctionLiteral))
+	default:
+		c.scope.names[name] = Any
+	}
+
+	t := c.expression(s.Value)
+	if declared == nil {
+		c.scope.names[name] = t
+	} else if e, got, want := c.misfit(declared, s.Value, t); e != nil {
+		c.errorf(start(e), "cannot use %s as %s in let %s", got, want, name)
+	}
+}
+
+// block checks the statements of block and returns the type of its value,
+// nil if it can't end without returning
+func (c *checker) block(block *ast.BlockStatement) Type {
+	if block == nil {
+		return Null
+	}
+
+	t := Type(Null)
+	for _, s := range block.Statements {
+		switch s := s.(type) {
+		case *ast.ExpressionStatement:
+			t = c.value(s.Expression)
+		case *ast.ReturnStatement:
+			c.statement(s)
+			t = nil
+		default:
+			c.statement(s)
+			t = Null
+		}
+	}
+	return t
+}
+
+// value is expression for the value of a block, which is nil for an if
+// that can't end without returning
+func (c *checker) value(e ast.Expression) Type {
+	if ie, ok := e.(*ast.IfExpression); ok {
+		return c.ifExpression(ie)
+	}
+	return c.expression(e)
+}
+
+func (c *checker) ifExpression(e *ast.IfExpression) Type {
+	c.expression(e.Condition)
+	consequence := c.block(e.Consequence)
+	alternative := Type(Null)
+	if e.Alternative != nil {
+		alternative = c.block(e.Alternative)
+	}
+	return join(consequence, alternative)
+}
+
+func (c *checker) expression(e ast.Expression) Type {
+	t := c.expr(e)
+	if e != nil {
+		c.types[e] = t
+	}
+	return t
+}
+
+func (c *checker) expr(e ast.Expression) Type {
+	switch e := e.(type) {
+	case nil:
+		return Any
+	case *ast.Identifier:
+		return c.scope.lookup(e.Value)
+	case *ast.IntegerLiteral:
+		return Int
+	case *ast.FloatLiteral:
+		return Float
+	case *ast.StringLiteral:
+		return String
+	case *ast.Boolean:
+		return Bool
+	case *ast.InterpolatedString:
+		c.expressions(e.Expressions)
+		return String
+	case *ast.PrefixExpression:
+		return c.prefix(e)
+	case *ast.InfixExpression:
+		return c.infix(e)
+	case *ast.IfExpression:
+		if t := c.ifExpression(e); t != nil {
+			return t
+		}
+		return Any
+	case *ast.FunctionLiteral:
+		return c.functionLiteral(e)
+	case *ast.CallExpression:
+		return c.call(e)
+	case *ast.ArrayLiteral:
+		return &Array{Element: joinAll(c.expressions(e.Elements))}
+	case *ast.SetLiteral:
+		elements := c.expressions(e.Elements)
+		for i, t := range elements {
+			if !hashable(t) {
+				c.errorf(start(e.Elements[i]), "unusable as set element: %s", t)
+			}
+		}
+		return &Set{Element: joinAll(elements)}
+	case *ast.HashLiteral:
+		return c.hash(e)
+	case *ast.IndexExpression:
+		return c.index(e)
+	case *ast.SliceExpression:
+		left := c.expression(e.Left)
+		for _, bound := range []ast.Expression{e.Start, e.End, e.Step} {
+			if bound != nil {
+				if t := c.expression(bound); !assignable(Int, t) {
+					c.errorf(start(bound), "slice bound must be int, got %s", t)
+				}
+			}
+		}
+		if _, ok := left.(*Array); ok || left == String || left == Any {
+			return left
+		}
+		c.errorf(start(e.Left), "slice operator not supported: %s", left)
+		return Any
+	case *ast.ImportExpression:
+		c.expression(e.Path)
+		return Any
+	case *ast.MacroLiteral:
+		return Any
+	}
+	panic(fmt.Sprintf("types: unexpected node type %T", e))
+}
+
+func (c *checker) expressions(exps []ast.Expression) []Type {
+	types := make([]Type, len(exps))
+	for i, e := range exps {
+		types[i] = c.expression(e)
+	}
+	return types
+}
+
+func (c *checker) prefix(e *ast.PrefixExpression) Type {
+	t := c.expression(e.Right)
+	if e.Operator == "!" {
+		return Bool
+	}
+	if isNumber(t) || t == Any {
+		return t
+	}
+	c.errorf(e.Token.Pos, "unsupported type for negative: %s", t)
+	return Any
+}
+
+func (c *checker) infix(e *ast.InfixExpression) Type {
+	left := c.expression(e.Left)
+	right := c.expression(e.Right)
+
+	switch e.Operator {
+	case "==", "!=":
+		return Bool
+
+	case "<", ">":
+		if (isNumber(left) || left == Any) && (isNumber(right) || right == Any) {
+			return Bool
+		}
+
+	case "in":
+		switch r := right.(type) {
+		case *Array:
+			return Bool
+		case *Set:
+			if hashable(left) {
+				return Bool
+			}
+			c.errorf(start(e.Left), "unusable as set element: %s", left)
+			return Bool
+		case *Hash:
+			if hashable(left) {
+				return Bool
+			}
+			c.errorf(start(e.Left), "unusable as hash key: %s", left)
+			return Bool
+		case Basic:
+			if r == Any || r == String && (left == String || left == Any) {
+				return Bool
+			}
+		}
+
+	default: // + - * /
+		switch {
+		case left == Any || right == Any:
+			return Any
+		case left == Int && right == Int:
+			return Int
+		case left == String && right == String && e.Operator == "+":
+			return String
+		case isNumber(left) && isNumber(right):
+			return Float
+		}
+	}
+
+	c.errorf(e.Token.Pos, "unsupported types for binary operation: %s %s %s", left, e.Operator, right)
+	return Any
+}
+
+func (c *checker) hash(e *ast.HashLiteral) Type {
+	keys := make([]Type, len(e.Keys))
+	values := make([]Type, len(e.Keys))
+	for i, k := range e.Keys {
+		keys[i] = c.expression(k)
+		if !hashable(keys[i]) {
+			c.errorf(start(k), "unusable as hash key: %s", keys[i])
+		}
+		values[i] = c.expression(e.Pairs[k])
+	}
+	return &Hash{Key: joinAll(keys), Value: joinAll(values)}
+}
+
+func (c *checker) index(e *ast.IndexExpression) Type {
+	left := c.expression(e.Left)
+	index := c.expression(e.Index)
+
+	switch l := left.(type) {
+	case *Array:
+		if assignable(Int, index) {
+			return l.Element
+		}
+	case *Hash:
+		if assignable(l.Key, index) {
+			return l.Value
+		}
+		// other keys are looked up as well, they are just never found
+		if hashable(index) {
+			return Any
+		}
+		c.errorf(start(e.Index), "unusable as hash key: %s", index)
+		return Any
+	case Basic:
+		if l == Any {
+			return Any
+		}
+		if l == String && assignable(Int, index) {
+			return String
+		}
+		if l != String {
+			c.errorf(start(e.Left), "index operator not supported: %s", left)
+			return Any
+		}
+	default:
+		c.errorf(start(e.Left), "index operator not supported: %s", left)
+		return Any
+	}
+
+	c.errorf(start(e.Index), "cannot index %s with %s", left, index)
+	return Any
+}
+
+func (c *checker) call(e *ast.CallExpression) Type {
+	callee := c.expression(e.Function)
+	args := c.expressions(e.Arguments)
+
+	switch f := callee.(type) {
+	case *Function:
+		if len(args) != len(f.Parameters) {
+			c.errorf(e.Token.Pos, "wrong number of arguments to %s: want=%d, got=%d",
+				calleeName(e), len(f.Parameters), len(args))
+			return f.Return
+		}
+		for i, arg := range args {
+			if m, got, want := c.misfit(f.Parameters[i], e.Arguments[i], arg); m != nil {
+				c.errorf(start(m), "cannot use %s as %s in argument %d to %s",
+					got, want, i+1, calleeName(e))
+			}
+		}
+		return f.Return
+	case Basic:
+		if f == Any {
+			return Any
+		}
+	}
+
+	c.errorf(start(e.Function), "calling non-function %s", callee)
+	return Any
+}
+
+// signature returns the type of fl as far as its annotations tell, the
+// result is any without annotation
+func (c *checker) signature(fl *ast.FunctionLiteral) *Function {
+	f := &Function{Parameters: make([]Type, len(fl.Parameters)), Return: Any}
+	for i := range fl.Parameters {
+		f.Parameters[i] = Any
+		if i < len(fl.ParameterTypes) && fl.ParameterTypes[i] != nil {
+			f.Parameters[i] = c.annotation(fl.ParameterTypes[i])
+		}
+	}
+	if fl.ReturnType != nil {
+		f.Return = c.annotation(fl.ReturnType)
+	}
+	return f
+}
+
+func (c *checker) functionLiteral(fl *ast.FunctionLiteral) Type {
+	f := c.signature(fl)
+
+	outerScope, outerFunction := c.scope, c.function
+	c.scope = newScope(outerScope)
+	c.function = &function{}
+	if fl.ReturnType != nil {
+		c.function.result = f.Return
+	}
+	defer func() { c.scope, c.function = outerScope, outerFunction }()
+
+	if fl.Name != "" {
+		c.scope.names[fl.Name] = f
+	}
+	for i, param := range fl.Parameters {
+		c.scope.names[param.Value] = f.Parameters[i]
+	}
+
+	// the value of the last statement is returned, nil if it's a return
+	if body := c.block(fl.Body); body != nil {
+		var last ast.Expression
+		if n := len(fl.Body.Statements); n > 0 {
+			if s, ok := fl.Body.Statements[n-1].(*ast.ExpressionStatement); ok {
+				last = s.Expression
+			}
+		}
+		c.checkReturn(last, body)
+		if last == nil && c.function.result != nil && !assignable(c.function.result, Null) {
+			c.errorf(fl.Body.Rbrace, "missing return of %s", c.function.result)
+		}
+	}
+
+	if fl.ReturnType == nil {
+		f.Return = c.function.returns
+		if f.Return == nil {
+			f.Return = Any
+		}
+	}
+	return f
+}
+
+// checkReturn checks value, of type t, returned by the current function,
+// value is nil for the null of a body which doesn't end with an expression
+func (c *checker) checkReturn(value ast.Expression, t Type) {
+	c.function.returns = join(c.function.returns, t)
+
+	if c.function.result == nil || value == nil {
+		return
+	}
+	if e, got, want := c.misfit(c.function.result, value, t); e != nil {
+		c.errorf(start(e), "cannot use %s as %s in return", got, want)
+	}
+}
+
+// misfit checks that e, of type t, can be used as a value of type to. If
+// not, it returns the part of e which doesn't fit, with its type and the
+// one wanted. The elements of literals are checked one by one, as their
+// joined type may be any.
+func (c *checker) misfit(to Type, e ast.Expression, t Type) (ast.Expression, Type, Type) {
+	switch e := e.(type) {
+	case *ast.ArrayLiteral:
+		if to, ok := to.(*Array); ok {
+			return c.misfitElements(to.Element, e.Elements)
+		}
+	case *ast.SetLiteral:
+		if to, ok := to.(*Set); ok {
+			return c.misfitElements(to.Element, e.Elements)
+		}
+	case *ast.HashLiteral:
+		if to, ok := to.(*Hash); ok {
+			for _, k := range e.Keys {
+				if m, got, want := c.misfit(to.Key, k, c.types[k]); m != nil {
+					return m, got, want
+				}
+				v := e.Pairs[k]
+				if m, got, want := c.misfit(to.Value, v, c.types[v]); m != nil {
+					return m, got, want
+				}
+			}
+			return nil, nil, nil
+		}
+	}
+
+	if assignable(to, t) {
+		return nil, nil, nil
+	}
+	return e, t, to
+}
+
+func (c *checker) misfitElements(to Type, elements []ast.Expression) (ast.Expression, Type, Type) {
+	for _, el := range elements {
+		if m, got, want := c.misfit(to, el, c.types[el]); m != nil {
+			return m, got, want
+		}
+	}
+	return nil, nil, nil
+}
+
+// annotation returns the type an annotation stands for, nil for none
+func (c *checker) annotation(te ast.TypeExpression) Type {
+	if te == nil {
+		return nil
+	}
+	if t, ok := c.annotations[te]; ok {
+		return t
+	}
+
+	var t Type
+	switch te := te.(type) {
+	case *ast.NamedType:
+		switch Basic(te.Name) {
+		case Any, Int, Float, String, Bool, Null:
+			t = Basic(te.Name)
+		default:
+			c.errorf(te.Token.Pos, "unknown type %s", te.Name)
+			t = Any
+		}
+	case *ast.ArrayType:
+		t = &Array{Element: c.annotation(te.Element)}
+	case *ast.SetType:
+		t = &Set{Element: c.annotation(te.Element)}
+	case *ast.HashType:
+		t = &Hash{Key: c.annotation(te.Key), Value: c.annotation(te.Value)}
+	case *ast.FunctionType:
+		f := &Function{Parameters: make([]Type, len(te.Parameters)), Return: Any}
+		for i, p := range te.Parameters {
+			f.Parameters[i] = c.annotation(p)
+		}
+		if te.Return != nil {
+			f.Return = c.annotation(te.Return)
+		}
+		t = f
+	default:
+		panic(fmt.Sprintf("types: unexpected annotation type %T", te))
+	}
+	c.annotations[te] = t
+	return t
+}
+
+func calleeName(call *ast.CallExpression) string {
+	if ident, ok := call.Function.(*ast.Identifier); ok {
+		return ident.Value
+	}
+	return "function"
+}
+
+func isFunctionLiteral(e ast.Expression) bool {
+	_, ok := e.(*ast.FunctionLiteral)
+	return ok
+}
+
+// start returns where the source of e starts
+func start(e ast.Expression) token.Position {
+	switch e := e.(type) {
+	case *ast.InfixExpression:
+		return start(e.Left)
+	case *ast.CallExpression:
+		return start(e.Function)
+	case *ast.IndexExpression:
+		return start(e.Left)
+	case *ast.SliceExpression:
+		return start(e.Left)
+	case *ast.Identifier:
+		return e.Token.Pos
+	case *ast.IntegerLiteral:
+		return e.Token.Pos
+	case *ast.FloatLiteral:
+		return e.Token.Pos
+	case *ast.StringLiteral:
+		return e.Token.Pos
+	case *ast.Boolean:
+		return e.Token.Pos
+	case *ast.InterpolatedString:
+		return e.Token.Pos
+	case *ast.PrefixExpression:
+		return e.Token.Pos
+	case *ast.IfExpression:
+		return e.Token.Pos
+	case *ast.FunctionLiteral:
+		return e.Token.Pos
+	case *ast.MacroLiteral:
+		return e.Token.Pos
+	case *ast.ArrayLiteral:
+		return e.Token.Pos
+	case *ast.SetLiteral:
+		return e.Token.Pos
+	case *ast.HashLiteral:
+		return e.Token.Pos
+	case *ast.ImportExpression:
+		return e.Token.Pos
+	}
+	return token.Position{}
+}
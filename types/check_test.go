@@ -0,0 +1,121 @@
+package types
+
+import (
+	"monkey/lexer"
+	"monkey/parser"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		// inferred without annotations
+		{`1 + 2.5; "a" + "b"; -1.5; !"a"; 1 < 2.0; [1] == "a"`, []string{}},
+		{`let f = fn(x) { x + 1 }; f("a") + 1`, []string{}},
+		{`1 + "a"`, []string{"1:3: unsupported types for binary operation: int + string"}},
+		{`"a" - "b"; "a" < "b"; -"a"`, []string{
+			"1:5: unsupported types for binary operation: string - string",
+			"1:16: unsupported types for binary operation: string < string",
+			"1:23: unsupported type for negative: string",
+		}},
+		{`let x = 1; let y = x + true`, []string{"1:22: unsupported types for binary operation: int + bool"}},
+		{`let a = [1, 2]; a[0] + "b"; a["x"]`, []string{
+			"1:22: unsupported types for binary operation: int + string",
+			"1:31: cannot index [int] with string",
+		}},
+		{`let h = {"a": 1}; h["a"] + 1; h[1]; 1[0]`, []string{
+			"1:37: index operator not supported: int",
+		}},
+		{`let h = {"a": 1}; h[1] + 1; h[[true]]; h[1.5]; h[{}]`, []string{
+			"1:42: unusable as hash key: float",
+			"1:50: unusable as hash key: {any: any}",
+		}},
+		{`{[1]: 1}; {{}: 1}; #{1.5}; 1.5 in {}`, []string{
+			"1:12: unusable as hash key: {any: any}",
+			"1:22: unusable as set element: float",
+			"1:28: unusable as hash key: float",
+		}},
+		{`let f = fn() { if (true) { return 1 } else { 2 } }; f() + "a"`, []string{
+			"1:57: unsupported types for binary operation: int + string",
+		}},
+		{`let f = fn() { if (true) { return 1 } }; f() + "a"`, []string{}},
+		{`1(2); fn(a) { a }(1, 2)`, []string{
+			"1:1: calling non-function int",
+			"1:18: wrong number of arguments to function: want=1, got=2",
+		}},
+		// annotations
+		{`let x: int = 1; let y: float = 1.5; let z: [string] = []; let h: {string: [int]} = {"a": [1]}`, []string{}},
+		{`let x: int = "a"; let y: [int] = [1, "b"]; let s: #{string} = #{1}`, []string{
+			"1:14: cannot use string as int in let x",
+			"1:38: cannot use string as int in let y",
+			"1:65: cannot use int as string in let s",
+		}},
+		{`let h: {string: [int]} = {"a": [1], "b": [2.5]}; let g: {string: any} = {"a": 1, "b": "c"}`, []string{
+			"1:43: cannot use float as int in let h",
+		}},
+		{`let x: int = 1; let y: string = x`, []string{"1:33: cannot use int as string in let y"}},
+		{`let f = fn(a: string, b: [int]) -> bool { len(b) > 0 }; f("x", [1]); f(1, ["y"])`, []string{
+			"1:72: cannot use int as string in argument 1 to f",
+			"1:76: cannot use string as int in argument 2 to f",
+		}},
+		{`let f = fn(a: int) -> int { if (a > 0) { return "pos" }; a }`, []string{
+			"1:49: cannot use string as int in return",
+		}},
+		{`let f = fn() -> string { 1 }; let g = fn() -> int { let x = 1; }`, []string{
+			"1:26: cannot use int as string in return",
+			"1:64: missing return of int",
+		}},
+		{`let fib = fn(n: int) -> int { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib("a")`, []string{
+			"1:88: cannot use string as int in argument 1 to fib",
+		}},
+		{`let apply = fn(f: fn(int) -> int, x: int) -> int { f(x) }; apply(fn(x: int) -> int { x }, 1); apply(fn(s: string) -> int { 1 }, 2)`, []string{
+			"1:101: cannot use fn(string) -> int as fn(int) -> int in argument 1 to apply",
+		}},
+		{`let x: number = 1; let f = fn(a: strng) { a }`, []string{
+			"1:8: unknown type number",
+			"1:34: unknown type strng",
+		}},
+	}
+
+	for _, tt := range tests {
+		program, err := parser.New(lexer.New(tt.input)).ParseProgram()
+		if err != nil {
+			t.Fatalf("%q: parse error %s", tt.input, err)
+		}
+
+		err = Check(program)
+		if len(tt.expected) == 0 {
+			if err != nil {
+				t.Errorf("%q: unexpected errors %s", tt.input, err)
+			}
+			continue
+		}
+		errors, ok := err.(ErrorList)
+		if !ok || len(errors) != len(tt.expected) {
+			t.Errorf("%q: wrong errors. want=%q, got=%v", tt.input, tt.expected, err)
+			continue
+		}
+		for i, e := range errors {
+			if e.Error() != tt.expected[i] {
+				t.Errorf("%q: error %d wrong. want=%q, got=%q", tt.input, i, tt.expected[i], e)
+			}
+		}
+	}
+}
+
+func TestCheckerKeepsGlobals(t *testing.T) {
+	checker := NewChecker()
+
+	program, _ := parser.New(lexer.New(`let x: string = "a"`)).ParseProgram()
+	if err := checker.Check(program); err != nil {
+		t.Fatalf("unexpected errors %s", err)
+	}
+
+	program, _ = parser.New(lexer.New(`x + 1`)).ParseProgram()
+	err := checker.Check(program)
+	if err == nil || err.Error() != "1:3: unsupported types for binary operation: string + int" {
+		t.Errorf("wrong error. got=%v", err)
+	}
+}
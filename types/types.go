@@ -0,0 +1,131 @@
+// Package types checks the optional type annotations of monkey programs and
+// the types it can infer without them, before the program gets compiled
+package types
+
+import "strings"
+
+// Type is the static type of an expression. Types are compared by their
+// String(), which is what an annotation of them looks like.
+type Type interface {
+	String() string
+}
+
+// Basic is one of the types without parts
+type Basic string
+
+const (
+	Any    Basic = "any" // anything, unknown types are any too
+	Int    Basic = "int"
+	Float  Basic = "float"
+	String Basic = "string"
+	Bool   Basic = "bool"
+	Null   Basic = "null"
+)
+
+func (b Basic) String() string { return string(b) }
+
+type Array struct {
+	Element Type
+}
+
+func (a *Array) String() string { return "[" + a.Element.String() + "]" }
+
+type Hash struct {
+	Key   Type
+	Value Type
+}
+
+func (h *Hash) String() string { return "{" + h.Key.String() + ": " + h.Value.String() + "}" }
+
+type Set struct {
+	Element Type
+}
+
+func (s *Set) String() string { return "#{" + s.Element.String() + "}" }
+
+type Function struct {
+	Parameters []Type
+	Return     Type
+}
+
+func (f *Function) String() string {
+	params := make([]string, len(f.Parameters))
+	for i, p := range f.Parameters {
+		params[i] = p.String()
+	}
+	return "fn(" + strings.Join(params, ", ") + ") -> " + f.Return.String()
+}
+
+// assignable tells whether a value of type from may be used where one of
+// type to is expected. As any stands for an unknown type it fits both ways.
+func assignable(to, from Type) bool {
+	if to == Any || from == Any {
+		return true
+	}
+
+	switch to := to.(type) {
+	case *Array:
+		from, ok := from.(*Array)
+		return ok && assignable(to.Element, from.Element)
+	case *Set:
+		from, ok := from.(*Set)
+		return ok && assignable(to.Element, from.Element)
+	case *Hash:
+		from, ok := from.(*Hash)
+		return ok && assignable(to.Key, from.Key) && assignable(to.Value, from.Value)
+	case *Function:
+		from, ok := from.(*Function)
+		if !ok || len(to.Parameters) != len(from.Parameters) {
+			return false
+		}
+		for i, p := range to.Parameters {
+			if !assignable(from.Parameters[i], p) {
+				return false
+			}
+		}
+		return assignable(to.Return, from.Return)
+	}
+	return to == from
+}
+
+// join returns the type of a value which is either of type a or b, nil
+// stands for no value at all, e.g. of a block ending with a return
+func join(a, b Type) Type {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case a.String() == b.String():
+		return a
+	}
+	return Any
+}
+
+// joinAll joins the types of the elements of a literal, any if there are
+// none
+func joinAll(types []Type) Type {
+	var t Type
+	for _, e := range types {
+		t = join(t, e)
+	}
+	if t == nil {
+		return Any
+	}
+	return t
+}
+
+func isNumber(t Type) bool {
+	return t == Int || t == Float
+}
+
+// hashable tells whether values of type t may be hash keys or set elements
+func hashable(t Type) bool {
+	switch t := t.(type) {
+	case Basic:
+		return t == Any || t == Int || t == String || t == Bool
+	case *Array:
+		return hashable(t.Element)
+	}
+	return false
+}
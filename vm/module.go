@@ -0,0 +1,74 @@
+package vm
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/compiler"
+	"monkey/evaluator"
+	"monkey/module"
+	"monkey/object"
+	"monkey/types"
+)
+
+// NewModuleLoader returns a loader which compiles every imported module and
+// runs it on a VM of its own, so that it gets its own globals
+func NewModuleLoader(searchPath ...string) *module.Loader {
+	return module.NewLoader(runModule, searchPath...)
+}
+
+func runModule(loader *module.Loader, program *ast.Program) (*object.Hash, error) {
+	macroEnv := object.NewEnvironment()
+	evaluator.DefineMacros(program, macroEnv)
+	if err := evaluator.ExpandMacros(program, macroEnv); err != nil {
+		return nil, err
+	}
+	if err := types.Check(program); err != nil {
+		return nil, err
+	}
+
+	symbolTable := compiler.NewSymbolTable()
+	for i, v := range object.Builtins {
+		symbolTable.DefineBuiltin(i, v.Name)
+	}
+
+	comp := compiler.NewWithState(symbolTable, []object.Object{})
+	err := comp.Compile(program)
+	if err != nil {
+		return nil, err
+	}
+
+	machine := New(comp.Bytecode())
+	machine.SetModuleLoader(loader)
+	err = machine.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	exports := object.NewHash()
+	for _, s := range symbolTable.Definitions() {
+		exports.Set(&object.String{Value: s.Name}, machine.globals[s.Index])
+	}
+	return exports, nil
+}
+
+// SetModuleLoader makes the VM share loaded modules with other VMs using the
+// same loader
+func (vm *VM) SetModuleLoader(loader *module.Loader) {
+	vm.loader = loader
+}
+
+func (vm *VM) executeImport(path object.Object) error {
+	str, ok := path.(*object.String)
+	if !ok {
+		return fmt.Errorf("import path must be STRING, got %s", path.Type())
+	}
+
+	if vm.loader == nil {
+		vm.loader = NewModuleLoader(module.DefaultSearchPath()...)
+	}
+	mod, err := vm.loader.Load(str.Value)
+	if err != nil {
+		return err
+	}
+	return vm.push(mod)
+}
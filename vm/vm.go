@@ -4,40 +4,48 @@ import (
 	"fmt"
 	"monkey/code"
 	"monkey/compiler"
+	"monkey/module"
 	"monkey/object"
+	"strings"
 )
 
 const StackSize = 2048
 const GlobalSize = 65536
 const MaxFrames = 1024
 
-var True = &object.Boolean{Value: true}
-var False = &object.Boolean{Value: false}
-var Null = &object.Null{}
+var True = object.TRUE
+var False = object.FALSE
+var Null = object.NULL
 
 type VM struct {
-	constants []object.Object
-	globals   []object.Object
+	globals []object.Object
 
 	stack []object.Object
 	sp    int // always ponts to the next value. Top of stack is stack[sp-1]
 
 	frames     []*Frame
 	frameIndex int
+
+	loader *module.Loader // loads modules for import expressions
 }
 
 func New(bytecode *compiler.Bytecode) *VM {
+	globals := make([]object.Object, GlobalSize)
+
 	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions}
-	mainClosure := &object.Closure{Fn: mainFn}
+	mainClosure := &object.Closure{
+		Fn:        mainFn,
+		Constants: bytecode.Constants,
+		Globals:   globals,
+	}
 	mainFrame := NewFrame(mainClosure, 0)
 	frames := make([]*Frame, MaxFrames)
 	frames[0] = mainFrame
 
 	return &VM{
-		constants: bytecode.Constants,
-		globals:   make([]object.Object, GlobalSize),
-		stack:     make([]object.Object, StackSize),
-		sp:        0,
+		globals: globals,
+		stack:   make([]object.Object, StackSize),
+		sp:      0,
 
 		frames:     frames,
 		frameIndex: 1,
@@ -47,6 +55,7 @@ func New(bytecode *compiler.Bytecode) *VM {
 func NewWithGlobalsStore(bytecode *compiler.Bytecode, globals []object.Object) *VM {
 	vm := New(bytecode)
 	vm.globals = globals
+	vm.frames[0].cl.Globals = globals
 	return vm
 }
 
@@ -76,11 +85,18 @@ func (vm *VM) StackTop() object.Object {
 }
 
 func (vm *VM) Run() error {
+	return vm.run(0)
+}
+
+// run executes instructions until the frame on top of baseFrame returns,
+// or the main frame runs out of instructions
+func (vm *VM) run(baseFrame int) error {
 	var ip int
 	var ins code.Instructions
 	var op code.Opcode
 
-	for vm.currentFrame().ip < len(vm.currentFrame().Instructions()) {
+	for vm.frameIndex > baseFrame &&
+		vm.currentFrame().ip < len(vm.currentFrame().Instructions()) {
 		ip = vm.currentFrame().ip
 		ins = vm.currentFrame().Instructions()
 		op = code.Opcode(ins[ip])
@@ -91,7 +107,7 @@ func (vm *VM) Run() error {
 		case code.OpConstant:
 			constIndex := code.ReadUint16(ins[ip+1:])
 			vm.updateIp(ip + 2)
-			err := vm.push(vm.constants[constIndex])
+			err := vm.push(vm.currentFrame().cl.Constants[constIndex])
 			if err != nil {
 				return err
 			}
@@ -142,11 +158,11 @@ func (vm *VM) Run() error {
 			globalIndex := code.ReadUint16(ins[ip+1:])
 			vm.updateIp(ip + 2) // skip over 2 bytes for argument
 
-			vm.globals[globalIndex] = vm.pop()
+			vm.currentFrame().cl.Globals[globalIndex] = vm.pop()
 		case code.OpGetGlobal:
 			globalIndex := code.ReadUint16(ins[ip+1:])
 			vm.updateIp(ip + 2) // skip over 2 bytes for argument
-			err := vm.push(vm.globals[globalIndex])
+			err := vm.push(vm.currentFrame().cl.Globals[globalIndex])
 			if err != nil {
 				return err
 			}
@@ -201,6 +217,55 @@ func (vm *VM) Run() error {
 			if err != nil {
 				return err
 			}
+		case code.OpConcat:
+			numParts := int(code.ReadUint16(ins[ip+1:]))
+			vm.updateIp(ip + 2) // skip over 2 bytes for argument
+
+			str := vm.buildString(vm.sp-numParts, vm.sp)
+			vm.sp -= numParts
+			err := vm.push(str)
+			if err != nil {
+				return err
+			}
+		case code.OpSlice:
+			step := vm.pop()
+			end := vm.pop()
+			start := vm.pop()
+			left := vm.pop()
+
+			sliced, err := object.Slice(left, start, end, step)
+			if err != nil {
+				return err
+			}
+			err = vm.push(sliced)
+			if err != nil {
+				return err
+			}
+		case code.OpSet:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.updateIp(ip + 2) // skip over 2 bytes for argument
+
+			set, err := vm.buildSet(vm.sp-numElements, vm.sp)
+			if err != nil {
+				return err
+			}
+			vm.sp -= numElements
+			err = vm.push(set)
+			if err != nil {
+				return err
+			}
+		case code.OpIn:
+			collection := vm.pop()
+			value := vm.pop()
+
+			ok, err := object.Contains(collection, value)
+			if err != nil {
+				return err
+			}
+			err = vm.push(nativeBoolToBooleanObject(ok))
+			if err != nil {
+				return err
+			}
 		case code.OpIndex:
 			index := vm.pop()
 			left := vm.pop()
@@ -247,6 +312,13 @@ func (vm *VM) Run() error {
 			if err != nil {
 				return err
 			}
+		case code.OpImport:
+			path := vm.pop()
+
+			err := vm.executeImport(path)
+			if err != nil {
+				return err
+			}
 		} // end of switch/case
 	} // end of for loop
 	return nil
@@ -274,7 +346,8 @@ func (vm *VM) updateIp(ip int) {
 }
 
 func (vm *VM) pushClosure(constIndex int, numFree int) error {
-	constant := vm.constants[constIndex]
+	current := vm.currentFrame().cl
+	constant := current.Constants[constIndex]
 	function, ok := constant.(*object.CompiledFunction)
 	if !ok {
 		return fmt.Errorf("not a function: %+v", constant)
@@ -285,7 +358,12 @@ func (vm *VM) pushClosure(constIndex int, numFree int) error {
 	}
 	vm.sp -= numFree
 
-	closure := &object.Closure{Fn: function, Free: free}
+	closure := &object.Closure{
+		Fn:        function,
+		Free:      free,
+		Constants: current.Constants,
+		Globals:   current.Globals,
+	}
 	return vm.push(closure)
 }
 
@@ -301,6 +379,9 @@ func (vm *VM) executeBinaryOperation(op code.Opcode) error {
 	if leftType == object.STRING_OBJ && rightType == object.STRING_OBJ {
 		return vm.executeBinaryStringOperation(op, left, right)
 	}
+	if isNumber(left) && isNumber(right) {
+		return vm.executeBinaryFloatOperation(op, left, right)
+	}
 	return fmt.Errorf("unsupported types for binary operation: %s %s",
 		leftType, rightType)
 }
@@ -326,6 +407,29 @@ func (vm *VM) executeBinaryIntergerOperation(op code.Opcode, left, right object.
 	return vm.push(&object.Integer{Value: result})
 }
 
+// executeBinaryFloatOperation computes with floats, integers mixed with
+// floats get converted
+func (vm *VM) executeBinaryFloatOperation(op code.Opcode, left, right object.Object) error {
+	leftValue, _ := object.ToFloat(left)
+	rightValue, _ := object.ToFloat(right)
+
+	var result float64
+	switch op {
+	case code.OpAdd:
+		result = leftValue + rightValue
+	case code.OpSub:
+		result = leftValue - rightValue
+	case code.OpMul:
+		result = leftValue * rightValue
+	case code.OpDiv:
+		result = leftValue / rightValue
+	default:
+		return fmt.Errorf("unknown float operator: %d", op)
+	}
+
+	return vm.push(&object.Float{Value: result})
+}
+
 func (vm *VM) executeBinaryStringOperation(op code.Opcode, left, right object.Object) error {
 	if op != code.OpAdd {
 		return fmt.Errorf("unknown string operator: %d", op)
@@ -344,6 +448,9 @@ func (vm *VM) executeComparison(op code.Opcode) error {
 	if left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ {
 		return vm.executeIntegerComparison(op, left, right)
 	}
+	if isNumber(left) && isNumber(right) {
+		return vm.executeFloatComparison(op, left, right)
+	}
 	switch op {
 	case code.OpEqual:
 		return vm.push(nativeBoolToBooleanObject(right == left))
@@ -370,6 +477,26 @@ func (vm *VM) executeIntegerComparison(op code.Opcode, left, right object.Object
 	}
 }
 
+func (vm *VM) executeFloatComparison(op code.Opcode, left, right object.Object) error {
+	leftValue, _ := object.ToFloat(left)
+	rightValue, _ := object.ToFloat(right)
+
+	switch op {
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(rightValue == leftValue))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(rightValue != leftValue))
+	case code.OpGreaterThan:
+		return vm.push(nativeBoolToBooleanObject(leftValue > rightValue))
+	default:
+		return fmt.Errorf("unknown operator: %d", op)
+	}
+}
+
+func isNumber(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
+}
+
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	if input {
 		return True
@@ -391,6 +518,9 @@ func (vm *VM) executeUnaryOperation(op code.Opcode) error {
 }
 
 func (vm *VM) executeUnaryMinusOperation(operand object.Object) error {
+	if f, ok := operand.(*object.Float); ok {
+		return vm.push(&object.Float{Value: -f.Value})
+	}
 	if operand.Type() != object.INTEGER_OBJ {
 		return fmt.Errorf("unsupported type for negative: %s", operand.Type())
 	}
@@ -433,28 +563,54 @@ func (vm *VM) buildArray(startIndex, endIndex int) object.Object {
 }
 
 func (vm *VM) buildHash(startIndex, endIndex int) (object.Object, error) {
-	hashedPairs := make(map[object.HashKey]object.HashPair)
+	hash := object.NewHash()
 
 	for i := startIndex; i < endIndex; i += 2 {
 		key := vm.stack[i]
 		value := vm.stack[i+1]
 
-		pair := object.HashPair{Key: key, Value: value}
-		hashKey, ok := key.(object.Hashable)
+		hashKey, ok := object.AsHashable(key)
 		if !ok {
 			return nil, fmt.Errorf("unusable as hash key: %s", key.Type())
 		}
-		hashedPairs[hashKey.HashKey()] = pair
+		hash.Set(hashKey, value)
+	}
+	return hash, nil
+}
+
+// buildString joins the values as they get displayed, e.g. by puts
+func (vm *VM) buildString(startIndex, endIndex int) object.Object {
+	var out strings.Builder
+
+	for i := startIndex; i < endIndex; i++ {
+		out.WriteString(vm.stack[i].Inspect())
+	}
+	return &object.String{Value: out.String()}
+}
+
+func (vm *VM) buildSet(startIndex, endIndex int) (object.Object, error) {
+	set := object.NewSet()
+
+	for i := startIndex; i < endIndex; i++ {
+		e, ok := object.AsHashable(vm.stack[i])
+		if !ok {
+			return nil, fmt.Errorf("unusable as set element: %s", vm.stack[i].Type())
+		}
+		set.Add(e)
 	}
-	return &object.Hash{Pairs: hashedPairs}, nil
+	return set, nil
 }
 
 func (vm *VM) executeIndexExpression(left, index object.Object) error {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return vm.executeArrayIndex(left, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return vm.executeStringIndex(left, index)
 	case left.Type() == object.HASH_OBJ:
 		return vm.executeHashIndex(left, index)
+	case left.Type() == object.MODULE_OBJ:
+		return vm.executeHashIndex(left.(*object.Module).Exports, index)
 	default:
 		return fmt.Errorf("index operator not supported: %s", left.Type())
 	}
@@ -465,25 +621,44 @@ func (vm *VM) executeArrayIndex(array, index object.Object) error {
 	i := index.(*object.Integer).Value
 	max := int64(len(arrayObject.Elements) - 1)
 
+	if i < 0 {
+		i += max + 1 // count from the end
+	}
 	if i < 0 || i > max {
 		return vm.push(Null)
 	}
 	return vm.push(arrayObject.Elements[i])
 }
 
+// executeStringIndex indexes by characters, the result is a string of one
+// character
+func (vm *VM) executeStringIndex(str, index object.Object) error {
+	runes := []rune(str.(*object.String).Value)
+	i := index.(*object.Integer).Value
+	max := int64(len(runes) - 1)
+
+	if i < 0 {
+		i += max + 1 // count from the end
+	}
+	if i < 0 || i > max {
+		return vm.push(Null)
+	}
+	return vm.push(&object.String{Value: string(runes[i])})
+}
+
 func (vm *VM) executeHashIndex(hash, index object.Object) error {
 	hashObject := hash.(*object.Hash)
 
-	key, ok := index.(object.Hashable)
+	key, ok := object.AsHashable(index)
 	if !ok {
 		return fmt.Errorf("unusable as hash key: %s", index.Type())
 	}
 
-	pair, ok := hashObject.Pairs[key.HashKey()]
+	value, ok := hashObject.Get(key)
 	if !ok {
 		return vm.push(Null)
 	}
-	return vm.push(pair.Value)
+	return vm.push(value)
 }
 
 func (vm *VM) executeCall(numArgs int) error {
@@ -503,7 +678,13 @@ func (vm *VM) callClosure(cl *object.Closure, numArgs int) error {
 		return fmt.Errorf("wrong number of arguments: want=%d, got=%d",
 			cl.Fn.NumParameters, numArgs)
 	}
+	if vm.frameIndex >= MaxFrames {
+		return fmt.Errorf("frame overflow: more than %d nested calls", MaxFrames)
+	}
 	frame := NewFrame(cl, vm.sp-numArgs)
+	if frame.bp+cl.Fn.NumLocals >= StackSize {
+		return fmt.Errorf("stack overflow")
+	}
 	vm.pushFrame(frame)
 	vm.sp = frame.bp + cl.Fn.NumLocals // NumLocals >= numArgs
 	return nil
@@ -512,7 +693,14 @@ func (vm *VM) callClosure(cl *object.Closure, numArgs int) error {
 func (vm *VM) callBuiltin(builtin *object.Builtin, numArgs int) error {
 	args := vm.stack[vm.sp-numArgs : vm.sp]
 
-	result := builtin.Fn(args...)
+	var result object.Object
+	if builtin.HigherOrderFn != nil {
+		// the stack gets reused by calls back into the VM
+		args = append([]object.Object{}, args...)
+		result = builtin.HigherOrderFn(vm.callFromBuiltin, args...)
+	} else {
+		result = builtin.Fn(args...)
+	}
 	vm.sp = vm.sp - numArgs - 1
 
 	if result != nil {
@@ -522,3 +710,52 @@ func (vm *VM) callBuiltin(builtin *object.Builtin, numArgs int) error {
 	}
 	return nil
 }
+
+// CallFunction calls a monkey function value from go, either after Run is
+// done or from within a builtin running on this VM. The function runs on top
+// of the current stack, and the VM state is restored once it returns.
+func (vm *VM) CallFunction(fn object.Object, args ...object.Object) (object.Object, error) {
+	sp := vm.sp
+	if sp >= StackSize {
+		return nil, fmt.Errorf("stack overflow")
+	}
+	baseFrame := vm.frameIndex
+	lastPopped := vm.stack[sp]
+
+	defer func() {
+		vm.sp = sp
+		vm.frameIndex = baseFrame
+		vm.stack[sp] = lastPopped
+	}()
+
+	err := vm.push(fn)
+	if err != nil {
+		return nil, err
+	}
+	for _, arg := range args {
+		err := vm.push(arg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = vm.executeCall(len(args))
+	if err != nil {
+		return nil, err
+	}
+	if vm.frameIndex > baseFrame {
+		err = vm.run(baseFrame)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return vm.pop(), nil
+}
+
+func (vm *VM) callFromBuiltin(fn object.Object, args ...object.Object) object.Object {
+	result, err := vm.CallFunction(fn, args...)
+	if err != nil {
+		return &object.Error{Message: err.Error()}
+	}
+	return result
+}
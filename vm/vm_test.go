@@ -4,16 +4,21 @@ import (
 	"fmt"
 	"monkey/ast"
 	"monkey/compiler"
+	"monkey/evaluator"
 	"monkey/lexer"
 	"monkey/object"
 	"monkey/parser"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
 func parse(input string) *ast.Program {
 	l := lexer.New(input)
 	p := parser.New(l)
-	return p.ParseProgram()
+	program, _ := p.ParseProgram()
+	return program
 }
 
 type vmTestCase struct {
@@ -111,6 +116,16 @@ func TestGlobalLetStatements(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+func TestConstStatements(t *testing.T) {
+	tests := []vmTestCase{
+		{"const one = 1; one", 1},
+		{"const one = 1; const two = one + one; one + two", 3},
+		{"let one = 1; let one = one + 1; one", 2},
+		{"const one = 1; let f = fn() { let one = 2; one }; f() + one", 3},
+	}
+	runVmTests(t, tests)
+}
+
 func TestArrayLiterals(t *testing.T) {
 	tests := []vmTestCase{
 		{`[]`, []int{}},
@@ -143,6 +158,314 @@ func TestHashLiterals(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+func TestHashInsertionOrder(t *testing.T) {
+	tests := []vmTestCase{
+		{`str({3: 1, 1: 2, 2: 3})`, "{3: 1, 1: 2, 2: 3}"},
+		{`str({"b": 1, "a": 2, "b": 3})`, "{b: 3, a: 2}"},
+		{`str({true: [], "x": {2: 0, 1: 0}})`, "{true: [], x: {2: 0, 1: 0}}"},
+		{`keys(json_parse(json_stringify({"b": 1, "a": 2})))`, []string{"a", "b"}},
+	}
+	runVmTests(t, tests)
+}
+
+func TestArrayHashKeys(t *testing.T) {
+	tests := []vmTestCase{
+		{`{[1, "a"]: 1, [1, "b"]: 2}[[1, "b"]]`, 2},
+		{`{[1, [true]]: 1}[[1, [true]]]`, 1},
+		{`{[1, 2]: 1}[[2, 1]]`, Null},
+		{`{[]: 1, [[]]: 2}[[[]]]`, 2},
+		{`str({[1, 2]: 1, [1, 2]: 2})`, "{[1, 2]: 2}"},
+		{`has({[1]: 1}, [1])`, true},
+		{`has({}, [1, {}])`, &object.Error{Message: "unusable as hash key: ARRAY"}},
+		{`keys(delete({[1]: 1, [2]: 2}, [1]))[0][0]`, 2},
+	}
+	runVmTests(t, tests)
+}
+
+func TestStringEscapes(t *testing.T) {
+	tests := []vmTestCase{
+		{`"a\tb"`, "a\tb"},
+		{`"\"quoted\""`, `"quoted"`},
+		{`len("\\n")`, 2},
+		{`"\u{48}i" + "\u{1F600}"`, "Hi😀"},
+		{"`a\\n\n\"b\"`", "a\\n\n\"b\""},
+		{"split(`x\ny`, \"\\n\")", []string{"x", "y"}},
+	}
+	runVmTests(t, tests)
+}
+
+func TestNumberLiterals(t *testing.T) {
+	tests := []vmTestCase{
+		{"0xff", 255},
+		{"0o755", 493},
+		{"0O17 + 0X_f", 30},
+		{"0", 0},
+		{"0b1111_0000", 240},
+		{"1_000_000 / 1_000", 1000},
+		{"1.5 + 1", 2.5},
+		{"1e3", 1000.0},
+		{"2.5e-1 * 4", 1.0},
+		{"-0x10", -16},
+	}
+	runVmTests(t, tests)
+}
+
+func TestComments(t *testing.T) {
+	tests := []vmTestCase{
+		{"// nothing\n1 # one", 1},
+		{"let a = 10; /* let a = 20; */ a / 2 // half", 5},
+		{"let s = #{1}; # a set\nlen(s)", 1},
+		{"/* /* nested */ */ \"//\" + \"#\"", "//#"},
+	}
+	runVmTests(t, tests)
+}
+
+func TestInterpolatedStrings(t *testing.T) {
+	tests := []vmTestCase{
+		{`let name = "monkey"; "hello ${name}!"`, "hello monkey!"},
+		{`"${1 + 2} = ${3}"`, "3 = 3"},
+		{`"${[1, "a"]} ${ {"k": true} } ${#{1}}"`, "[1, a] {k: true} #{1}"},
+		{`let f = fn(x) { "<${x}>" }; "${f("${f(1)}")}"`, "<<1>>"},
+		{`"${puts()}"`, "null"},
+		{`"\${x}"`, "${x}"},
+		{`"a${"b"}c" + "d"`, "abcd"},
+	}
+	runVmTests(t, tests)
+}
+
+func TestStringIndexing(t *testing.T) {
+	tests := []vmTestCase{
+		{`"abc"[0]`, "a"},
+		{`"abc"[2]`, "c"},
+		{`"abc"[-1]`, "c"},
+		{`"abc"[3]`, Null},
+		{`"abc"[-4]`, Null},
+		{`""[0]`, Null},
+		{`"日本語"[1]`, "本"},
+		{`"héllo"[1]`, "é"},
+		{`let s = "ab😀c"; s[len(s) - 2]`, "😀"},
+		{`chars("日本")`, []string{"日", "本"}},
+		{`chars("")`, []string{}},
+		{`join(reverse(chars("héllo")), "")`, "olléh"},
+		{`map(chars("ab"), upper)`, []string{"A", "B"}},
+		{`let größe = 1; let 名前 = "x"; größe + len(名前)`, 2},
+	}
+	runVmTests(t, tests)
+}
+
+func TestSliceExpression(t *testing.T) {
+	tests := []vmTestCase{
+		{"[1, 2, 3, 4][1:3]", []int{2, 3}},
+		{"[1, 2, 3, 4][:2]", []int{1, 2}},
+		{"[1, 2, 3, 4][2:]", []int{3, 4}},
+		{"[1, 2, 3, 4][:]", []int{1, 2, 3, 4}},
+		{"[1, 2, 3, 4][:-1]", []int{1, 2, 3}},
+		{"[1, 2, 3, 4][-2:]", []int{3, 4}},
+		{"[1, 2, 3, 4][-10:10]", []int{1, 2, 3, 4}},
+		{"[1, 2, 3, 4][3:1]", []int{}},
+		{"[1, 2, 3, 4, 5][::2]", []int{1, 3, 5}},
+		{"[1, 2, 3, 4, 5][1::2]", []int{2, 4}},
+		{"[1, 2, 3, 4][::-1]", []int{4, 3, 2, 1}},
+		{"[1, 2, 3, 4][2::-1]", []int{3, 2, 1}},
+		{"[1, 2, 3, 4][:0:-2]", []int{4, 2}},
+		{"[1, 2, 3, 4][-1:-3:-1]", []int{4, 3}},
+		{"let a = [1, 2, 3]; let n = 1; a[n:n + 1]", []int{2}},
+		{`"hello"[1:3]`, "el"},
+		{`"hello"[:-1]`, "hell"},
+		{`"hello"[::-1]`, "olleh"},
+		{`"héllo"[1:2]`, "é"},
+		{`""[1:]`, ""},
+		{"[1, 2, 3][1::9223372036854775807]", []int{2}},
+		{"[1, 2, 3][::-9223372036854775807 - 1]", []int{3}},
+		{"[1, 2, 3][-9223372036854775807 - 1:9223372036854775807:9223372036854775807]", []int{1}},
+		{"[1, 2, 3][9223372036854775807:-9223372036854775807 - 1:-9223372036854775807]", []int{3}},
+		{`"abc"[1::9223372036854775807]`, "b"},
+		{`"abc"[::-9223372036854775807 - 1]`, "c"},
+	}
+	runVmTests(t, tests)
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{`[1, 2][::0]`, "slice step must not be zero"},
+		{`[1, 2]["a":]`, "slice indices must be INTEGER, got STRING"},
+		{`{}[1:2]`, "slice operator not supported: HASH"},
+	}
+	for _, tt := range errors {
+		program := parse(tt.input)
+
+		comp := compiler.New()
+		err := comp.Compile(program)
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		err = vm.Run()
+		if err == nil {
+			t.Fatalf("expected VM error but resulted in none: %s", tt.input)
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong VM error: want=%q, got=%q", tt.expected, err)
+		}
+	}
+}
+
+func TestSetLiterals(t *testing.T) {
+	tests := []vmTestCase{
+		{`str(#{})`, "#{}"},
+		{`str(#{3, 1, 3, 2, 1})`, "#{3, 1, 2}"},
+		{`str(#{[1, 2], [1, 2], "a", true})`, "#{[1, 2], a, true}"},
+		{`len(#{1, 1 + 0, 2})`, 2},
+		{`let s = #{1, 2}; 2 in s`, true},
+		{`3 in #{1, 2}`, false},
+		{`[1] in #{[1]}`, true},
+		{`"a" in {"a": 1}`, true},
+		{`"b" in {"a": 1}`, false},
+		{`2 in [1, 2, 3]`, true},
+		{`[2] in [[1], [2]]`, true},
+		{`4 in [1, 2, 3]`, false},
+		{`"ell" in "hello"`, true},
+		{`"x" in "hello"`, false},
+		{`if (1 in #{1}) { 10 } else { 20 }`, 10},
+	}
+	runVmTests(t, tests)
+}
+
+func TestSetErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`#{1, {}}`, "unusable as set element: HASH"},
+		{`{} in #{1}`, "unusable as set element: HASH"},
+		{`[{}] in {}`, "unusable as hash key: ARRAY"},
+		{`1 in "abc"`, "type mismatch: INTEGER in STRING"},
+		{`1 in 2`, "unknown operator: INTEGER in INTEGER"},
+	}
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+
+		comp := compiler.New()
+		err := comp.Compile(program)
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		err = vm.Run()
+		if err == nil {
+			t.Fatalf("expected VM error but resulted in none: %s", tt.input)
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong VM error: want=%q, got=%q", tt.expected, err)
+		}
+	}
+}
+
+func TestSetBuiltins(t *testing.T) {
+	tests := []vmTestCase{
+		{`str(set([3, 1, 3]))`, "#{3, 1}"},
+		{`to_array(#{2, 1, 2})`, []int{2, 1}},
+		{`to_array(set([]))`, []int{}},
+		{`to_array(union(#{1, 2}, #{3, 2}))`, []int{1, 2, 3}},
+		{`to_array(intersection(#{1, 2, 3}, #{3, 2, 4}))`, []int{2, 3}},
+		{`to_array(difference(#{1, 2, 3}, #{2}))`, []int{1, 3}},
+		{`len(intersection(#{1}, #{2}))`, 0},
+		{`set([{}])`, &object.Error{Message: "unusable as set element: HASH"}},
+		{`union(#{1}, [1])`, &object.Error{Message: "argument 2 to `union` must be SET, got ARRAY"}},
+		{`to_array([1])`, &object.Error{Message: "argument 1 to `to_array` must be SET, got ARRAY"}},
+	}
+	runVmTests(t, tests)
+}
+
+func TestMacros(t *testing.T) {
+	tests := []vmTestCase{
+		{`let unless = macro(cond, cons, alt) {
+			quote(if (!(unquote(cond))) { unquote(cons) } else { unquote(alt) })
+		};
+		unless(10 > 5, 1, 2)`, 2},
+		{`let twice = macro(x) { quote(unquote(x) + unquote(x)) }; twice(1 + 2)`, 6},
+		{`let plus = macro(a, b) { quote(unquote(a) + unquote(b)) };
+		let nested = macro(a) { quote(plus(unquote(a), 10)) };
+		nested(plus(1, 2))`, 13},
+		{`let m = macro() { let n = 2 * 3; quote(unquote(n) + unquote(n > 5)) }; m()`,
+			"unsupported types for binary operation: INTEGER BOOLEAN"},
+		// the bindings of the quote don't capture the arguments
+		{`let swap = macro(a, b) {
+			quote(fn() { let t = unquote(a); [unquote(b), t] }())
+		};
+		let t = 1;
+		swap(t, 2)`, []int{2, 1}},
+		{`let inc = macro(x) { quote(fn(y) { y + unquote(x) }) };
+		let y = 10;
+		inc(y)(1)`, 11},
+	}
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+		env := object.NewEnvironment()
+		evaluator.DefineMacros(program, env)
+		if err := evaluator.ExpandMacros(program, env); err != nil {
+			t.Fatalf("%s: expansion error: %s", tt.input, err)
+		}
+
+		comp := compiler.New()
+		if err := comp.Compile(program); err != nil {
+			t.Fatalf("%s: compiler error: %s", tt.input, err)
+		}
+
+		vm := New(comp.Bytecode())
+		err := vm.Run()
+		if msg, ok := tt.expected.(string); ok {
+			if err == nil || err.Error() != msg {
+				t.Errorf("%s: wrong VM error. want=%q, got=%v", tt.input, msg, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: vm error: %s", tt.input, err)
+		}
+		testExpectedObject(t, tt.input, tt.expected, vm.LastPoppedStackElem())
+	}
+}
+
+func TestMacroErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let m = macro(a) { quote(a) };\nm(1, 2)", "2:1: macro m: wrong number of arguments: want=1, got=2"},
+		{"let m = macro() { 1 };\nlet x = m();", "2:9: macro m: must return QUOTE, got INTEGER"},
+		{"let m = macro() { quote(unquote(if (false) { 1 })) }; m()", "1:55: macro m: cannot unquote NULL"},
+		{"let m = macro() { quote(m()) }; m()", "1:25: macro m: expansion nested too deeply"},
+		{"let m = macro() { x }; [m()]", "1:25: macro m: identifier not found: x"},
+	}
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+		env := object.NewEnvironment()
+		evaluator.DefineMacros(program, env)
+
+		err := evaluator.ExpandMacros(program, env)
+		if err == nil {
+			t.Errorf("%q: expected expansion error", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("%q: wrong error. want=%q, got=%q", tt.input, tt.expected, err)
+		}
+	}
+
+	comp := compiler.New()
+	err := comp.Compile(parse("let f = fn() { macro(x) { x } }"))
+	if err == nil || err.Error() != "1:16: macro literal must be bound by a top-level let" {
+		t.Errorf("wrong compiler error. got=%v", err)
+	}
+}
+
 func TestIndexExpression(t *testing.T) {
 	tests := []vmTestCase{
 		{"[1,2,3][1]", 2},
@@ -150,7 +473,9 @@ func TestIndexExpression(t *testing.T) {
 		{"[[1,1,1][0]][0]", 1},
 		{"[][0]", Null},
 		{"[1,2,3][99]", Null},
-		{"[1][-1]", Null},
+		{"[1][-1]", 1},
+		{"[1, 2, 3][-3]", 1},
+		{"[1, 2, 3][-4]", Null},
 		{"{1: 1, 2: 2}[1]", 1},
 		{"{1: 1, 2: 2}[2]", 2},
 		{"{1: 1}[0]", Null},
@@ -406,6 +731,8 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
+		{`len("héllo wörld")`, 11},
+		{`len("日本語")`, 3},
 		{`len(1)`,
 			&object.Error{
 				Message: "argument to `len` not supported, got INTEGER",
@@ -429,6 +756,309 @@ func TestBuiltinFunctions(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+func TestStringBuiltins(t *testing.T) {
+	tests := []vmTestCase{
+		{`split("a,b,,c", ",")`, []string{"a", "b", "", "c"}},
+		{"split(\"  a b\tc \")", []string{"a", "b", "c"}},
+		{`split("héllo", "")`, []string{"h", "é", "l", "l", "o"}},
+		{`join(["a", "b", "c"], "-")`, "a-b-c"},
+		{`join([], "-")`, ""},
+		{`join([1], "-")`, &object.Error{Message: "elements to `join` must be STRING, got INTEGER"}},
+		{"trim(\"  hi \n\")", "hi"},
+		{`trim("xxhixx", "x")`, "hi"},
+		{`upper("éa")`, "ÉA"},
+		{`lower("ÀB")`, "àb"},
+		{`contains("monkey", "key")`, true},
+		{`contains("monkey", "donkey")`, false},
+		{`replace("a-b-c", "-", "+")`, "a+b+c"},
+		{`index_of("héllo", "l")`, 2},
+		{`index_of("hello", "z")`, -1},
+		{`substr("héllo", 1, 3)`, "éll"},
+		{`substr("héllo", 3)`, "lo"},
+		{`substr("héllo", 3, 99)`, "lo"},
+		{`substr("héllo", 9)`, ""},
+		{`substr("héllo", 1, -1)`, &object.Error{Message: "length to `substr` must not be negative, got -1"}},
+		{`starts_with("monkey", "mon")`, true},
+		{`ends_with("monkey", "mon")`, false},
+		{`format("%s is %d, %v", "x", 42, true)`, "x is 42, true"},
+		{`format("%s", [1, 2])`, "[1, 2]"},
+		{`str(42)`, "42"},
+		{`str("42")`, "42"},
+		{`str([1, "a"])`, "[1, a]"},
+		{`int("42")`, 42},
+		{`int(" -7 ")`, -7},
+		{`int(true)`, 1},
+		{`int("4x")`, &object.Error{Message: "could not parse \"4x\" as integer"}},
+		{`upper(1)`, &object.Error{Message: "argument 1 to `upper` must be STRING, got INTEGER"}},
+		{`replace("a", "b")`, &object.Error{Message: "wrong number of arguments, got=2, want=3"}},
+	}
+	runVmTests(t, tests)
+}
+
+func TestCollectionBuiltins(t *testing.T) {
+	tests := []vmTestCase{
+		{`first([1, 2, 3])`, 1},
+		{`first([])`, Null},
+		{`last([1, 2, 3])`, 3},
+		{`last([])`, Null},
+		{`rest([1, 2, 3])`, []int{2, 3}},
+		{`rest([])`, Null},
+		{`first(1)`, &object.Error{Message: "argument 1 to `first` must be ARRAY, got INTEGER"}},
+		{`map([1, 2, 3], fn(x) { x * 2 })`, []int{2, 4, 6}},
+		{`map(["a", "b"], upper)`, []string{"A", "B"}},
+		{`let n = 10; map([1, 2], fn(x) { x + n })`, []int{11, 12}},
+		{`map([1, 2], fn(x, y) { x })`, &object.Error{Message: "wrong number of arguments: want=2, got=1"}},
+		{`map([1, 2], 1)`, &object.Error{Message: "argument 2 to `map` must be FUNCTION, got INTEGER"}},
+		{`filter([1, 2, 3, 4], fn(x) { x > 2 })`, []int{3, 4}},
+		{`reduce([1, 2, 3, 4], 0, fn(acc, x) { acc + x })`, 10},
+		{`reduce([], 5, fn(acc, x) { acc + x })`, 5},
+		{`sort([3, 1, 2])`, []int{1, 2, 3}},
+		{`sort(["b", "c", "a"])`, []string{"a", "b", "c"}},
+		{`sort([3, 1, 2], fn(a, b) { a > b })`, []int{3, 2, 1}},
+		{`sort([3, 1, 2], fn(a, b) { b - a })`, []int{3, 2, 1}},
+		{`sort([1, "a"])`, &object.Error{Message: "cannot compare STRING with INTEGER"}},
+		{`keys({"b": 1, "a": 2})`, []string{"b", "a"}},
+		{`values({"b": 1, "a": 2})`, []int{1, 2}},
+		{`keys(delete({"c": 1, "a": 2, "b": 3}, "a"))`, []string{"c", "b"}},
+		{`delete({1: 1, 2: 2}, 1)`, map[object.HashKey]int64{
+			(&object.Integer{Value: 2}).HashKey(): 2,
+		}},
+		{`has({1: 1}, 1)`, true},
+		{`has({1: 1}, 2)`, false},
+		{`range(3)`, []int{0, 1, 2}},
+		{`range(1, 4)`, []int{1, 2, 3}},
+		{`range(5, 0, -2)`, []int{5, 3, 1}},
+		{`range(1, 2, 0)`, &object.Error{Message: "step to `range` must not be zero"}},
+		{`range(0, 9223372036854775807, 4611686018427387904)`, []int{0, 4611686018427387904}},
+		{`range(9223372036854775807, -9223372036854775807 - 1, -4611686018427387904)`,
+			[]int{9223372036854775807, 4611686018427387903, -1, -4611686018427387905}},
+		{`range(-9223372036854775807 - 1, 9223372036854775807, -1)`, []int{}},
+		{`range(9223372036854775807)`, &object.Error{Message: "`range` of 9223372036854775807 elements is too large, at most 16777216"}},
+		{`sort([1, 2], fn(a, b) { let x = 1; })`, &object.Error{Message: "comparator of `sort` must return BOOLEAN or INTEGER, got NULL"}},
+		{`map(zip([1, 2, 3], [4, 5]), fn(p) { p[0] * p[1] })`, []int{4, 10}},
+		{`reverse([1, 2, 3])`, []int{3, 2, 1}},
+		{`slice([1, 2, 3, 4], 1, 3)`, []int{2, 3}},
+		{`slice([1, 2, 3, 4], -2)`, []int{3, 4}},
+		{`slice([1, 2, 3, 4], 3, 1)`, []int{}},
+		{`
+		let sum = fn(arr) { reduce(arr, 0, fn(acc, x) { acc + x }) };
+		sum(map([[1, 2], [3]], sum))
+		`, 6},
+		{`
+		let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } };
+		map(range(8), fib)
+		`, []int{0, 1, 1, 2, 3, 5, 8, 13}},
+	}
+	runVmTests(t, tests)
+}
+
+func TestCallFunction(t *testing.T) {
+	symbolTable := compiler.NewSymbolTable()
+	for i, v := range object.Builtins {
+		symbolTable.DefineBuiltin(i, v.Name)
+	}
+	globals := make([]object.Object, GlobalSize)
+
+	comp := compiler.NewWithState(symbolTable, []object.Object{})
+	err := comp.Compile(parse(`
+	let base = 10;
+	let add = fn(a, b) { a + b + base };
+	let twice = fn(f, x) { f(f(x)) };
+	let broken = fn() { 1 + "a" };
+	let countDown = fn(x) { if (x == 0) { 0 } else { countDown(x - 1) } };
+	let forever = fn() { forever() };
+	99;
+	`))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	vm := NewWithGlobalsStore(comp.Bytecode(), globals)
+	err = vm.Run()
+	if err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+
+	global := func(name string) object.Object {
+		symbol, ok := symbolTable.Resolve(name)
+		if !ok {
+			t.Fatalf("%s not defined", name)
+		}
+		return globals[symbol.Index]
+	}
+
+	result, err := vm.CallFunction(global("add"), &object.Integer{Value: 1}, &object.Integer{Value: 2})
+	if err != nil {
+		t.Fatalf("CallFunction error: %s", err)
+	}
+	testExpectedObject(t, "add(1, 2)", 13, result)
+
+	inc, err := vm.CallFunction(global("add"), &object.Integer{Value: 1}, &object.Integer{Value: 1})
+	if err != nil {
+		t.Fatalf("CallFunction error: %s", err)
+	}
+	testExpectedObject(t, "add(1, 1)", 12, inc)
+
+	// a closure calling back a builtin
+	result, err = vm.CallFunction(global("twice"), object.GetBuiltinByName("upper"), &object.String{Value: "a"})
+	if err != nil {
+		t.Fatalf("CallFunction error: %s", err)
+	}
+	testExpectedObject(t, `twice(upper, "a")`, "A", result)
+
+	// a builtin called directly
+	result, err = vm.CallFunction(object.GetBuiltinByName("len"), &object.String{Value: "abc"})
+	if err != nil {
+		t.Fatalf("CallFunction error: %s", err)
+	}
+	testExpectedObject(t, `len("abc")`, 3, result)
+
+	errorTests := []struct {
+		fn       object.Object
+		args     []object.Object
+		expected string
+	}{
+		{global("base"), nil, "calling non-function and non-built-in"},
+		{global("add"), []object.Object{&object.Integer{Value: 1}}, "wrong number of arguments: want=2, got=1"},
+		{global("broken"), nil, "unsupported types for binary operation: INTEGER STRING"},
+		{global("countDown"), []object.Object{&object.Integer{Value: StackSize}}, "stack overflow"},
+		{global("forever"), nil, "frame overflow: more than 1024 nested calls"},
+	}
+	for _, tt := range errorTests {
+		_, err := vm.CallFunction(tt.fn, tt.args...)
+		if err == nil {
+			t.Errorf("expected error %q", tt.expected)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err)
+		}
+	}
+
+	// a call from a builtin when the stack is already full
+	vm.sp = StackSize
+	_, err = vm.CallFunction(global("add"), &object.Integer{Value: 1}, &object.Integer{Value: 2})
+	if err == nil || err.Error() != "stack overflow" {
+		t.Errorf("wrong error on a full stack. want=%q, got=%v", "stack overflow", err)
+	}
+	vm.sp = 0
+
+	// the VM is still usable after failed calls, and its state is untouched
+	result, err = vm.CallFunction(global("countDown"), &object.Integer{Value: 10})
+	if err != nil {
+		t.Fatalf("CallFunction error: %s", err)
+	}
+	testExpectedObject(t, "countDown(10)", 0, result)
+	testExpectedObject(t, "99", 99, vm.LastPoppedStackElem())
+	if vm.sp != 0 || vm.frameIndex != 1 {
+		t.Errorf("VM state not restored. sp=%d, frameIndex=%d", vm.sp, vm.frameIndex)
+	}
+}
+
+func TestMathBuiltins(t *testing.T) {
+	tests := []vmTestCase{
+		{`abs(-3)`, 3},
+		{`abs(sqrt(4) * -1)`, 2.0},
+		{`min(3, 1, 2)`, 1},
+		{`max([3, 1, 2])`, 3},
+		{`max(1, sqrt(9))`, 3.0},
+		{`min([])`, &object.Error{Message: "`min` needs at least one number"}},
+		{`max(1, "2")`, &object.Error{Message: "argument 2 to `max` must be INTEGER or FLOAT, got STRING"}},
+		{`pow(2, 10)`, 1024},
+		{`pow(2, -1)`, 0.5},
+		{`pow(3, 39)`, 4052555153018976267},
+		{`pow(-2, 63)`, -9223372036854775808},
+		{`pow(2, 64)`, 18446744073709551616.0},
+		{`pow(1, 10000000000)`, 1},
+		{`pow(-1, 10000000001)`, -1},
+		{`pow(2, 10000000000) > 0`, true},
+		{`sqrt(16)`, 4.0},
+		{`sqrt(-1)`, &object.Error{Message: "argument to `sqrt` must not be negative, got -1"}},
+		{`floor(mean([1, 2]))`, 1},
+		{`ceil(mean([1, 2]))`, 2},
+		{`round(mean([1, 2]))`, 2},
+		{`round(-1 * mean([1, 2]))`, -2},
+		{`floor(7)`, 7},
+		{`round(pow(0, -1))`, &object.Error{Message: "argument to `round` must be finite, got +Inf"}},
+		{`floor(pow(-1, 0.5))`, &object.Error{Message: "argument to `floor` must be finite, got NaN"}},
+		{`ceil(pow(2, 64))`, &object.Error{Message: "argument to `ceil` out of INTEGER range, got 1.8446744073709552e+19"}},
+		{`clamp(5, 1, 3)`, 3},
+		{`clamp(-5, 1, 3)`, 1},
+		{`clamp(2, 1, 3)`, 2},
+		{`clamp(2, 3, 1)`, &object.Error{Message: "bounds to `clamp` are reversed: 3 > 1"}},
+		{`sum([1, 2, 3])`, 6},
+		{`sum([])`, 0},
+		{`sum([1, sqrt(4)])`, 3.0},
+		{`sum([1, "a"])`, &object.Error{Message: "elements to `sum` must be INTEGER or FLOAT"}},
+		{`mean([1, 2, 3, 4])`, 2.5},
+		{`mean([])`, &object.Error{Message: "`mean` of empty array"}},
+		{`random() < 1`, true},
+		{`rand_int(5) < 5`, true},
+		{`rand_int(5, 6)`, 5},
+		{`rand_int(3, 3)`, &object.Error{Message: "empty range for `rand_int`: [3, 3)"}},
+		{`let n = rand_int(-9223372036854775807 - 1, 9223372036854775807); n < 9223372036854775807`, true},
+		{`rand_int(-9223372036854775807 - 1, -9223372036854775807)`, -9223372036854775808},
+		{`rand_int(9223372036854775806, 9223372036854775807)`, 9223372036854775806},
+		{`sqrt(2) * sqrt(2) > 1`, true},
+		{`mean([1, 2]) + 1`, 2.5},
+		{`-mean([1, 2])`, -1.5},
+		{`mean([1, 2]) == mean([2, 1])`, true},
+		{`mean([1, 2]) < 2`, true},
+	}
+	runVmTests(t, tests)
+}
+
+func TestSeedRandom(t *testing.T) {
+	run := func() object.Object {
+		comp := compiler.New()
+		err := comp.Compile(parse(`[random(), rand_int(1000), rand_int(10, 20)]`))
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		vm := New(comp.Bytecode())
+		if err := vm.Run(); err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+		return vm.LastPoppedStackElem()
+	}
+
+	object.SeedRandom(42)
+	first := run().Inspect()
+	object.SeedRandom(42)
+	second := run().Inspect()
+	if first != second {
+		t.Errorf("same seed gave different results: %s != %s", first, second)
+	}
+}
+
+func TestJSONBuiltins(t *testing.T) {
+	tests := []vmTestCase{
+		{`json_parse("42")`, 42},
+		{`json_parse(" -1.5e1 ")`, -15.0},
+		{`json_parse("[1, 2, 3]")`, []int{1, 2, 3}},
+		{`json_parse("null")`, Null},
+		{`json_parse("[true, false]")[0]`, true},
+		{`json_parse(json_stringify("aé"))`, "aé"},
+		{`json_parse(json_stringify({"a": {"b": [2]}}))["a"]["b"][0]`, 2},
+		{`json_parse("[1, 2")`, &object.Error{Message: "invalid JSON: unexpected end of JSON input"}},
+		{`json_parse("1 2")`, &object.Error{Message: "invalid JSON: unexpected data after top-level value"}},
+		{`json_parse("{1: 2}")`, &object.Error{Message: "invalid JSON: object member name must be a string"}},
+		{`json_stringify(42)`, "42"},
+		{`json_stringify(sqrt(4))`, "2.0"},
+		{`json_stringify("a<b")`, `"a<b"`},
+		{`json_stringify([1, "a", true, puts()])`, `[1,"a",true,null]`},
+		{`json_stringify({"b": 1, "a": [], 3: {}})`, `{"3":{},"a":[],"b":1}`},
+		{`json_stringify({"1": 1, 1: 2})`, &object.Error{Message: `duplicate JSON key "1"`}},
+		{`json_stringify([{true: 1, "true": 2}])`, &object.Error{Message: `duplicate JSON key "true"`}},
+		{`json_stringify({"a": [1, 2]}, 2)`, "{\n  \"a\": [\n    1,\n    2\n  ]\n}"},
+		{`json_stringify([1], "--")`, "[\n--1\n]"},
+		{`json_stringify([1], true)`, &object.Error{Message: "argument 2 to `json_stringify` must be INTEGER or STRING, got BOOLEAN"}},
+		{`json_stringify(fn() {})`, &object.Error{Message: "unsupported type for JSON: CLOSURE"}},
+		{`json_stringify(json_parse(json_stringify({"k": [1, pow(2, -1), "x"]})))`, `{"k":[1,0.5,"x"]}`},
+	}
+	runVmTests(t, tests)
+}
+
 func TestClosures(t *testing.T) {
 	tests := []vmTestCase{
 		{
@@ -559,6 +1189,86 @@ func TestRecursiveFunctions(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+func TestImport(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"math.monkey": `
+			let add = fn(a, b) { a + b };
+			let inc = fn(a) { add(a, 1) };
+			const two = 2;
+		`,
+		"lib/greet.monkey": `
+			let m = import "math";
+			let greet = fn(name) { "hello " + name };
+			let four = m["add"](m["two"], 2);
+		`,
+		"a.monkey": `let b = import "b";`,
+		"b.monkey": `let a = import "a";`,
+	}
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []vmTestCase{
+		{`let m = import "math"; m["add"](1, 2)`, 3},
+		{`import "math.monkey"["two"]`, 2},
+		{`let add = fn(a) { a }; import "math"["inc"](add(1))`, 2},
+		{`import "math"["missing"]`, Null},
+		{`let g = import "greet"; g["greet"]("monkey")`, "hello monkey"},
+		{`import "greet"["four"]`, 4},
+		{`import "math" == import "greet"["m"]`, true},
+	}
+
+	loader := NewModuleLoader(dir, filepath.Join(dir, "lib"))
+	for _, tt := range tests {
+		comp := compiler.New()
+		err := comp.Compile(parse(tt.input))
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		vm.SetModuleLoader(loader)
+		err = vm.Run()
+		if err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+		testExpectedObject(t, tt.input, tt.expected, vm.LastPoppedStackElem())
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "nothing"`, "module \"nothing\" not found"},
+		{`import "a"`, "import cycle detected"},
+	}
+	for _, tt := range errorTests {
+		comp := compiler.New()
+		err := comp.Compile(parse(tt.input))
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		vm.SetModuleLoader(loader)
+		err = vm.Run()
+		if err == nil {
+			t.Fatalf("expected VM error for %q", tt.input)
+		}
+		if !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("wrong VM error for %q. want it to contain %q, got=%q",
+				tt.input, tt.expected, err)
+		}
+	}
+}
+
 func runVmTests(t *testing.T, tests []vmTestCase) {
 	t.Helper()
 
@@ -593,6 +1303,11 @@ func testExpectedObject(t *testing.T, input string, expected interface{}, actual
 		if err != nil {
 			t.Errorf("testIntegerObject failed: %s", err)
 		}
+	case float64:
+		err := testFloatObject(input, expected, actual)
+		if err != nil {
+			t.Errorf("testFloatObject failed: %s", err)
+		}
 	case bool:
 		err := testBooleanObject(input, bool(expected), actual)
 		if err != nil {
@@ -608,6 +1323,11 @@ func testExpectedObject(t *testing.T, input string, expected interface{}, actual
 		if err != nil {
 			t.Errorf("testArrayObject failed: %s", err)
 		}
+	case []string:
+		err := testStringArrayObject(input, expected, actual)
+		if err != nil {
+			t.Errorf("testStringArrayObject failed: %s", err)
+		}
 	case map[object.HashKey]int64:
 		err := testHashObject(input, expected, actual)
 		if err != nil {
@@ -645,6 +1365,20 @@ func testIntegerObject(input string, expected int64, actual object.Object) error
 	return nil
 }
 
+func testFloatObject(input string, expected float64, actual object.Object) error {
+	result, ok := actual.(*object.Float)
+	if !ok {
+		return fmt.Errorf("`%s`: object is not Float. got=%T (%+v)",
+			input, actual, actual)
+	}
+	if result.Value != expected {
+		return fmt.Errorf("`%s`: object has wrong value. got=%v, want=%v",
+			input, result.Value, expected)
+	}
+
+	return nil
+}
+
 func testBooleanObject(input string, expected bool, actual object.Object) error {
 	result, ok := actual.(*object.Boolean)
 	if !ok {
@@ -688,24 +1422,48 @@ func testArrayObject(input string, expected []int, actual object.Object) error {
 	return nil
 }
 
+func testStringArrayObject(input string, expected []string, actual object.Object) error {
+	result, ok := actual.(*object.Array)
+	if !ok {
+		return fmt.Errorf("`%s`: object is not array. got=%T (%+v)", input, actual, actual)
+	}
+
+	if len(result.Elements) != len(expected) {
+		return fmt.Errorf("wrong number of elements: want=%d, got=%d",
+			len(expected), len(result.Elements))
+	}
+	for i, expectedElem := range expected {
+		err := testStringObject(input+fmt.Sprintf("[%d]", i), expectedElem, result.Elements[i])
+		if err != nil {
+			return fmt.Errorf("testStringObject failed: %s", err)
+		}
+	}
+	return nil
+}
+
 func testHashObject(input string, expected map[object.HashKey]int64, actual object.Object) error {
 	hash, ok := actual.(*object.Hash)
 	if !ok {
 		return fmt.Errorf("object is not hash, got=%T (%+v)", actual, actual)
 	}
 
-	if len(hash.Pairs) != len(expected) {
+	if hash.Len() != len(expected) {
 		return fmt.Errorf("hash has wrong number of pairs. want=%d, got=%d",
-			len(expected), len(hash.Pairs))
+			len(expected), hash.Len())
+	}
+
+	values := make(map[object.HashKey]object.Object)
+	for _, pair := range hash.Pairs() {
+		values[pair.Key.(object.Hashable).HashKey()] = pair.Value
 	}
 
 	for expectedKey, expectedValue := range expected {
-		pair, ok := hash.Pairs[expectedKey]
+		value, ok := values[expectedKey]
 		if !ok {
 			return fmt.Errorf("no pair for given key in Pairs")
 		}
 
-		err := testIntegerObject(input+fmt.Sprintf("[%v]", expectedKey), expectedValue, pair.Value)
+		err := testIntegerObject(input+fmt.Sprintf("[%v]", expectedKey), expectedValue, value)
 		if err != nil {
 			return fmt.Errorf("testIntegerObject failed: %s", err)
 		}